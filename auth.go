@@ -6,57 +6,266 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
-type twitchauth struct {
-	ClientID     string
-	ClientSecret string
-	AccessToken  string
-	AccessExpiry time.Time
+const (
+	tokenRenewMargin   = time.Duration(10) * time.Second
+	tokenStoreRetryMin = time.Duration(5) * time.Second
+	tokenStoreRetryMax = time.Duration(5) * time.Minute
+)
+
+type Token struct {
+	ClientID     string    `json:"client_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
 }
 
-func getDefaultAuth() (a *twitchauth, err error) {
-	if defaultTwitchClientID == "" || defaultTwitchClientSecret == "" {
-		return nil, errNoDefaultAuth
+type TokenSource interface {
+	Token() (Token, error)
+	Refresh(rejected Token) (Token, error)
+}
+
+// A StoredTokenSource keeps working with a renewed token even when its store
+// hook fails, so StoreErr is where that failure shows up until a later
+// attempt saves it.
+type StoredTokenSource interface {
+	TokenSource
+	StoreErr() error
+}
+
+func (t Token) expiring() bool {
+	return !t.Expiry.IsZero() && time.Until(t.Expiry) <= tokenRenewMargin
+}
+
+type appTokenSource struct {
+	mu      sync.Mutex
+	flights flightGroup
+
+	app   OAuthApp
+	token Token
+}
+
+// NewAppTokenSource talks to the default Twitch endpoints over
+// http.DefaultClient. Use OAuthApp.AppTokenSource to set either.
+func NewAppTokenSource(clientID string, clientSecret string) TokenSource {
+	return OAuthApp{ClientID: clientID, ClientSecret: clientSecret}.AppTokenSource()
+}
+
+func (a OAuthApp) AppTokenSource() TokenSource {
+	return &appTokenSource{
+		app:   a,
+		token: Token{ClientID: a.ClientID},
+	}
+}
+
+func getDefaultAuth(app OAuthApp) (tokens TokenSource, err error) {
+	if app.ClientID == "" {
+		app.ClientID = os.Getenv("TWITCH_CLIENT_ID")
+	}
+	if app.ClientSecret == "" {
+		app.ClientSecret = os.Getenv("TWITCH_CLIENT_SECRET")
 	}
 
-	a = &twitchauth{
-		ClientID:     defaultTwitchClientID,
-		ClientSecret: defaultTwitchClientSecret,
+	if app.ClientID == "" || app.ClientSecret == "" {
+		return nil, errNoDefaultAuth
 	}
-	err = a.update()
+
+	tokens = app.AppTokenSource()
+	_, err = tokens.Token()
 	return
 }
 
-func (a *twitchauth) update() (err error) {
-	if a.AccessToken != "" && time.Until(a.AccessExpiry) > time.Duration(10)*time.Second {
-		return nil
+func (a *appTokenSource) Token() (token Token, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token.AccessToken != "" && !a.token.expiring() {
+		return a.token, nil
 	}
 
-	q := url.Values{
-		"client_id":     {a.ClientID},
-		"client_secret": {a.ClientSecret},
-		"grant_type":    {"client_credentials"},
+	err = a.renew()
+	return a.token, err
+}
+
+func (a *appTokenSource) Refresh(rejected Token) (token Token, err error) {
+	found, err := a.flights.do("refresh:"+rejected.AccessToken, func() (interface{}, error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		if a.token.AccessToken != rejected.AccessToken {
+			return a.token, nil
+		}
+
+		err := a.renew()
+		return a.token, err
+	})
+	if err != nil {
+		return
 	}
 
-	url := "https://id.twitch.tv/oauth2/token?" + q.Encode()
-	req, err := http.NewRequest("POST", url, nil)
+	return found.(Token), nil
+}
+
+func (a *appTokenSource) renew() (err error) {
+	token, err := a.app.requestToken(url.Values{
+		"client_id":     {a.token.ClientID},
+		"client_secret": {a.app.ClientSecret},
+		"grant_type":    {"client_credentials"},
+	})
 	if err != nil {
 		return
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	a.token.AccessToken = token.AccessToken
+	a.token.Expiry = token.Expiry
+	return
+}
+
+type userTokenSource struct {
+	mu      sync.Mutex
+	flights flightGroup
+
+	app        OAuthApp
+	token      Token
+	store      func(Token) error
+	storeErr   error
+	storeFails int
+	storeRetry time.Time
+}
+
+// NewUserTokenSource talks to the default Twitch endpoints over
+// http.DefaultClient. Use OAuthApp.TokenSource to set either.
+func NewUserTokenSource(clientSecret string, token Token, store func(Token) error) StoredTokenSource {
+	return OAuthApp{ClientID: token.ClientID, ClientSecret: clientSecret}.TokenSource(token, store)
+}
+
+func (a OAuthApp) TokenSource(token Token, store func(Token) error) StoredTokenSource {
+	token.ClientID = a.ClientID
+	token.AccessToken = strings.TrimPrefix(token.AccessToken, "oauth:")
+	return &userTokenSource{
+		app:   a,
+		token: token,
+		store: store,
+	}
+}
+
+func (u *userTokenSource) Token() (token Token, err error) {
+	u.mu.Lock()
+	if u.storeErr != nil && time.Now().After(u.storeRetry) {
+		u.save(u.token)
+	}
+	current := u.token
+	u.mu.Unlock()
+
+	if current.RefreshToken == "" || !current.expiring() {
+		return current, nil
+	}
+
+	return u.Refresh(current)
+}
+
+func (u *userTokenSource) Refresh(rejected Token) (token Token, err error) {
+	found, err := u.flights.do("refresh:"+rejected.AccessToken, func() (interface{}, error) {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		if u.token.AccessToken != rejected.AccessToken {
+			return u.token, nil
+		}
+
+		if u.token.RefreshToken == "" {
+			return u.token, ErrTokenNotRenewable
+		}
+
+		q := url.Values{
+			"client_id":     {u.token.ClientID},
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {u.token.RefreshToken},
+		}
+		if u.app.ClientSecret != "" {
+			q.Set("client_secret", u.app.ClientSecret)
+		}
+
+		renewed, err := u.app.requestToken(q)
+		if err != nil {
+			return u.token, err
+		}
+
+		renewed.ClientID = u.token.ClientID
+		if renewed.RefreshToken == "" {
+			renewed.RefreshToken = u.token.RefreshToken
+		}
+
+		u.token = renewed
+		if u.store != nil {
+			// Twitch may have rotated the refresh token, and the old one
+			// stops working. The renewal still stands if saving it fails,
+			// so keep trying now and then until it's saved.
+			u.storeFails = 0
+			u.save(renewed)
+		}
+
+		return renewed, nil
+	})
+
+	token, _ = found.(Token)
+	return
+}
+
+func (u *userTokenSource) save(token Token) {
+	u.storeErr = u.store(token)
+	if u.storeErr != nil {
+		u.storeRetry = time.Now().Add(backoffDelay(u.storeFails, tokenStoreRetryMin, tokenStoreRetryMax))
+		u.storeFails++
+	}
+}
+
+func (u *userTokenSource) StoreErr() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.storeErr
+}
+
+type staticTokenSource struct {
+	token Token
+}
+
+func NewStaticTokenSource(clientID string, accessToken string) TokenSource {
+	return staticTokenSource{Token{
+		ClientID:    clientID,
+		AccessToken: strings.TrimPrefix(accessToken, "oauth:"),
+	}}
+}
+
+func (s staticTokenSource) Token() (Token, error) {
+	return s.token, nil
+}
+
+func (s staticTokenSource) Refresh(rejected Token) (Token, error) {
+	return s.token, ErrTokenNotRenewable
+}
+
+func (a OAuthApp) requestToken(q url.Values) (token Token, err error) {
+	resp, err := a.httpClient().PostForm(a.endpoint("/token"), q)
 	if err != nil {
 		return
 	}
 
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = oauthResponseError(resp)
+		return
+	}
 
 	type CredsResponse struct {
-		TokenType   string `json:"token_type"`
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int    `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
 	}
 
 	var cr CredsResponse
@@ -66,19 +275,23 @@ func (a *twitchauth) update() (err error) {
 		return
 	}
 
-	if cr.TokenType != "bearer" {
+	if !strings.EqualFold(cr.TokenType, "bearer") {
 		err = errTwitchAuthTokenType
 		return
 	}
 
-	a.AccessToken = "Bearer " + cr.AccessToken
-	a.AccessExpiry = time.Now().Add(time.Duration(cr.ExpiresIn) * time.Second)
+	token = Token{
+		AccessToken:  cr.AccessToken,
+		RefreshToken: cr.RefreshToken,
+	}
+
+	if cr.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(cr.ExpiresIn) * time.Second)
+	}
+
 	return
 }
 
 var errNoDefaultAuth = errors.New("can't find default client settings")
+var errNoClientID = errors.New("user tokens need a client id")
 var errTwitchAuthTokenType = errors.New("don't understand twitch auth token type")
-var (
-	defaultTwitchClientID     = os.Getenv("TWITCH_CLIENT_ID")
-	defaultTwitchClientSecret = os.Getenv("TWITCH_CLIENT_SECRET")
-)
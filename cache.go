@@ -0,0 +1,130 @@
+package retwitch
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheSize = 256
+	defaultCacheTTL  = time.Duration(1) * time.Hour
+)
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Expired   uint64
+	Entries   int
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type lruCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+	stats   CacheStats
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (lc *lruCache) get(key string) (value interface{}, ok bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	elem, ok := lc.entries[key]
+	if !ok {
+		lc.stats.Misses++
+		return
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		lc.removeElement(elem)
+		lc.stats.Expired++
+		lc.stats.Misses++
+		return nil, false
+	}
+
+	lc.order.MoveToFront(elem)
+	lc.stats.Hits++
+	return entry.value, true
+}
+
+func (lc *lruCache) set(key string, value interface{}) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	var expires time.Time
+	if lc.ttl > 0 {
+		expires = time.Now().Add(lc.ttl)
+	}
+
+	if elem, ok := lc.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		lc.order.MoveToFront(elem)
+		return
+	}
+
+	lc.entries[key] = lc.order.PushFront(&cacheEntry{key, value, expires})
+	for lc.size > 0 && lc.order.Len() > lc.size {
+		lc.removeElement(lc.order.Back())
+		lc.stats.Evictions++
+	}
+}
+
+func (lc *lruCache) remove(key string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if elem, ok := lc.entries[key]; ok {
+		lc.removeElement(elem)
+	}
+}
+
+func (lc *lruCache) removeElement(elem *list.Element) {
+	lc.order.Remove(elem)
+	delete(lc.entries, elem.Value.(*cacheEntry).key)
+}
+
+func (lc *lruCache) snapshot() (stats CacheStats) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	stats = lc.stats
+	stats.Entries = lc.order.Len()
+	return
+}
+
+func cacheSize(size int) int {
+	if size == 0 {
+		return defaultCacheSize
+	}
+
+	return size
+}
+
+func cacheTTL(ttl time.Duration) time.Duration {
+	if ttl == 0 {
+		return defaultCacheTTL
+	}
+
+	return ttl
+}
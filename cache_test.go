@@ -0,0 +1,115 @@
+package retwitch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCacheOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []string
+		present []string
+		evicted []string
+	}{
+		{
+			name:    "oldest goes first",
+			ops:     []string{"set a", "set b", "set c", "set d"},
+			present: []string{"b", "c", "d"},
+			evicted: []string{"a"},
+		},
+		{
+			name:    "get refreshes",
+			ops:     []string{"set a", "set b", "set c", "get a", "set d"},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+		{
+			name:    "set refreshes",
+			ops:     []string{"set a", "set b", "set c", "set a", "set d"},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+		{
+			name:    "remove makes room",
+			ops:     []string{"set a", "set b", "set c", "remove b", "set d"},
+			present: []string{"a", "c", "d"},
+			evicted: []string{"b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lc := newLRUCache(3, 0)
+			for _, op := range test.ops {
+				switch key := op[len(op)-1:]; op[:len(op)-2] {
+				case "set":
+					lc.set(key, key)
+				case "get":
+					lc.get(key)
+				case "remove":
+					lc.remove(key)
+				}
+			}
+
+			for _, key := range test.present {
+				if value, ok := lc.get(key); !ok || value != key {
+					t.Errorf("%s = %v, %v", key, value, ok)
+				}
+			}
+
+			for _, key := range test.evicted {
+				if _, ok := lc.get(key); ok {
+					t.Errorf("%s wasn't evicted", key)
+				}
+			}
+		})
+	}
+}
+
+func TestLRUCacheTTL(t *testing.T) {
+	lc := newLRUCache(0, 20*time.Millisecond)
+	lc.set("a", 1)
+	if _, ok := lc.get("a"); !ok {
+		t.Fatal("fresh entry missing")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	lc.set("b", 2)
+	if _, ok := lc.get("a"); ok {
+		t.Error("expired entry was returned")
+	}
+	if _, ok := lc.get("b"); !ok {
+		t.Error("fresh entry missing")
+	}
+
+	stats := lc.snapshot()
+	want := CacheStats{Hits: 2, Misses: 1, Expired: 1, Entries: 1}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestChannelCacheKeepsJoined(t *testing.T) {
+	c := &Client{
+		channels: newLRUCache(1, 0),
+		pinned:   map[string]*ChannelInfo{},
+	}
+
+	joined := c.pinChannel("joined", "1")
+	c.getRoomChannel("other", "2")
+	c.getRoomChannel("another", "3")
+
+	if c.cachedChannel("joined") != joined {
+		t.Error("joined channel was evicted")
+	}
+
+	if c.cachedChannel("other") != nil {
+		t.Error("unjoined channel wasn't evicted")
+	}
+
+	c.unpinChannel("joined")
+	if _, ok := c.pinned["joined"]; ok {
+		t.Error("parted channel is still pinned")
+	}
+}
@@ -2,71 +2,247 @@ package retwitch
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/lrstanley/girc"
 )
 
 type ChannelInfo struct {
-	Client     *Client
-	Name       string
-	id         string
-	cheerMatch *regexp.Regexp
-	cheerInfo  map[string]HelixCheermote
-	badges     map[string]HelixChatBadge
+	Client *Client
+	Name   string
+	id     string
+
+	roommu sync.Mutex
+	room   RoomState
+}
+
+type cheermoteSet struct {
+	match *regexp.Regexp
+	info  map[string]HelixCheermote
+}
+
+type RoomState struct {
+	EmoteOnly       bool `json:"emote_only"`
+	FollowersOnly   bool `json:"followers_only"`
+	FollowerMinutes int  `json:"follower_minutes,omitempty"`
+	R9K             bool `json:"r9k"`
+	SlowSeconds     int  `json:"slow_seconds,omitempty"`
+	SubsOnly        bool `json:"subs_only"`
 }
 
 func (c *Client) GetChannel(name string) (ch *ChannelInfo, err error) {
-	if cch, cached := c.channels[name]; cached {
-		return cch, nil
+	c.chanmu.Lock()
+	ch = c.cachedChannel(name)
+	c.chanmu.Unlock()
+
+	if ch != nil {
+		return ch, nil
 	}
 
-	helix, err := c.Helix()
+	found, err := c.flights.do("channel:"+name, func() (interface{}, error) {
+		helix, err := c.Helix()
+		if err != nil {
+			return nil, err
+		}
+
+		chid, err := helix.GetUserID(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.getRoomChannel(name, chid), nil
+	})
 	if err != nil {
 		return
 	}
 
-	chid, err := helix.GetUserID(name)
-	if err != nil {
-		return
+	return found.(*ChannelInfo), nil
+}
+
+func (c *Client) getRoomChannel(name string, roomID string) *ChannelInfo {
+	c.chanmu.Lock()
+	defer c.chanmu.Unlock()
+
+	if ch := c.cachedChannel(name); ch != nil {
+		return ch
 	}
 
-	ch = &ChannelInfo{
+	ch := &ChannelInfo{
 		Client: c,
 		Name:   name,
-		id:     chid,
+		id:     roomID,
 	}
 
-	c.channels[name] = ch
-	return
+	c.channels.set(name, ch)
+	return ch
+}
+
+func (c *Client) cachedChannel(name string) *ChannelInfo {
+	if ch, pinned := c.pinned[name]; pinned {
+		return ch
+	}
+
+	if cch, cached := c.channels.get(name); cached {
+		return cch.(*ChannelInfo)
+	}
+
+	return nil
+}
+
+// Joined channels stay out of the LRU, since Twitch only sends the room
+// state in full on join and evicting it would lose track of the modes.
+func (c *Client) pinChannel(name string, roomID string) *ChannelInfo {
+	ch := c.getRoomChannel(name, roomID)
+
+	c.chanmu.Lock()
+	c.pinned[name] = ch
+	c.channels.remove(name)
+	c.chanmu.Unlock()
+
+	return ch
+}
+
+func (c *Client) unpinChannel(name string) {
+	c.chanmu.Lock()
+	delete(c.pinned, name)
+	c.channels.remove(name)
+	c.chanmu.Unlock()
+}
+
+func (c *Client) ChannelCacheStats() CacheStats {
+	return c.channels.snapshot()
+}
+
+func (c *Client) AssetCacheStats() CacheStats {
+	return c.assets.snapshot()
+}
+
+func (c *Client) cachedAsset(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if value, cached := c.assets.get(key); cached {
+		return value, nil
+	}
+
+	return c.refreshAsset(key, fetch)
+}
+
+func (c *Client) refreshAsset(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	return c.flights.do("asset:"+key, func() (interface{}, error) {
+		value, err := fetch()
+		if err == nil {
+			c.assets.set(key, value)
+		}
+
+		return value, err
+	})
+}
+
+func (c *ChannelInfo) RoomState() RoomState {
+	c.roommu.Lock()
+	defer c.roommu.Unlock()
+	return c.room
 }
 
-func (c *ChannelInfo) resolveCheermotes() (err error) {
-	if c.cheerInfo != nil {
-		return nil
+func (c *ChannelInfo) updateRoomState(tags girc.Tags) RoomState {
+	c.roommu.Lock()
+	defer c.roommu.Unlock()
+
+	if value, ok := tags.Get("emote-only"); ok {
+		c.room.EmoteOnly = value == "1"
+	}
+
+	if value, ok := tags.Get("followers-only"); ok {
+		minutes, err := strconv.Atoi(value)
+		c.room.FollowersOnly = err == nil && minutes >= 0
+		c.room.FollowerMinutes = 0
+		if c.room.FollowersOnly {
+			c.room.FollowerMinutes = minutes
+		}
+	}
+
+	if value, ok := tags.Get("r9k"); ok {
+		c.room.R9K = value == "1"
+	}
+
+	if value, ok := tags.Get("slow"); ok {
+		c.room.SlowSeconds, _ = strconv.Atoi(value)
+	}
+
+	if value, ok := tags.Get("subs-only"); ok {
+		c.room.SubsOnly = value == "1"
+	}
+
+	return c.room
+}
+
+func (c *ChannelInfo) Refresh() (err error) {
+	_, err = c.Client.refreshAsset(c.cheermoteKey(), func() (interface{}, error) {
+		return c.fetchCheermotes(true)
+	})
+	if err != nil {
+		return
 	}
 
+	_, err = c.Client.refreshAsset(c.badgeKey(), func() (interface{}, error) {
+		return c.fetchBadges(true)
+	})
+	return
+}
+
+func (c *ChannelInfo) Invalidate() (err error) {
+	c.Client.assets.remove(c.cheermoteKey())
+	c.Client.assets.remove(c.badgeKey())
+
 	helix, err := c.Client.Helix()
 	if err != nil {
 		return
 	}
 
-	prefixes, infos, err := helix.GetCheermotes(c.id)
+	return helix.forget(c.cheermoteKey(), c.badgeKey())
+}
+
+func (c *ChannelInfo) cheermoteKey() string {
+	return cheermoteCacheKey(c.id)
+}
+
+func (c *ChannelInfo) badgeKey() string {
+	return badgeCacheKey(c.id)
+}
+
+func (c *ChannelInfo) resolveCheermotes() (cheers *cheermoteSet, err error) {
+	found, err := c.Client.cachedAsset(c.cheermoteKey(), func() (interface{}, error) {
+		return c.fetchCheermotes(false)
+	})
 	if err != nil {
 		return
 	}
 
+	return found.(*cheermoteSet), nil
+}
+
+func (c *ChannelInfo) fetchCheermotes(fresh bool) (interface{}, error) {
+	helix, err := c.Client.Helix()
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes, infos, err := helix.cheermotes(c.id, fresh)
+	if err != nil {
+		return nil, err
+	}
+
 	cmPattern, err := regexp.Compile("\\b(" + strings.Join(prefixes, "|") + ")([0-9]+)\\b")
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	c.cheerMatch = cmPattern
-	c.cheerInfo = infos
-	return
+	return &cheermoteSet{cmPattern, infos}, nil
 }
 
 func (c *ChannelInfo) GetEmoteURL(emoteID string) (emoteURL string, err error) {
-	if c.cheerInfo != nil {
-		if cminfo, iscm := c.cheerInfo[emoteID]; iscm {
+	if cheers, cached := c.Client.assets.get(c.cheermoteKey()); cached {
+		if cminfo, iscm := cheers.(*cheermoteSet).info[emoteID]; iscm {
 			emoteURL = cminfo.ImageURL
 			return
 		}
@@ -78,36 +254,60 @@ func (c *ChannelInfo) GetEmoteURL(emoteID string) (emoteURL string, err error) {
 }
 
 func (c *ChannelInfo) GetBadgeURL(badgeID string) (badgeURL string, err error) {
-	var helix *HelixAPI
-	if c.badges == nil || c.Client.badges == nil {
-		helix, err = c.Client.Helix()
-		if err != nil {
-			return
-		}
-	}
-
-	if c.badges == nil {
-		c.badges, err = helix.GetChannelChatBadges(c.id)
-		if err != nil {
-			return
-		}
+	badges, err := c.channelBadges()
+	if err != nil {
+		return
 	}
 
-	if c.Client.badges == nil {
-		c.Client.badges, err = helix.GetGlobalChatBadges()
-		if err != nil {
-			return
-		}
+	if badgeInfo, ok := badges[badgeID]; ok {
+		return badgeInfo.ImageURL, nil
 	}
 
-	if badgeInfo, ok := c.badges[badgeID]; ok {
-		return badgeInfo.ImageURL, nil
+	globalBadges, err := c.Client.globalBadges()
+	if err != nil {
+		return
 	}
 
-	if badgeInfo, ok := c.Client.badges[badgeID]; ok {
+	if badgeInfo, ok := globalBadges[badgeID]; ok {
 		return badgeInfo.ImageURL, nil
 	}
 
 	err = ErrNoSuchBadge
 	return
 }
+
+func (c *ChannelInfo) channelBadges() (badges map[string]HelixChatBadge, err error) {
+	found, err := c.Client.cachedAsset(c.badgeKey(), func() (interface{}, error) {
+		return c.fetchBadges(false)
+	})
+	if err != nil {
+		return
+	}
+
+	return found.(map[string]HelixChatBadge), nil
+}
+
+func (c *ChannelInfo) fetchBadges(fresh bool) (interface{}, error) {
+	helix, err := c.Client.Helix()
+	if err != nil {
+		return nil, err
+	}
+
+	return helix.channelChatBadges(c.id, fresh)
+}
+
+func (c *Client) globalBadges() (badges map[string]HelixChatBadge, err error) {
+	found, err := c.cachedAsset("badges:global", func() (interface{}, error) {
+		helix, err := c.Helix()
+		if err != nil {
+			return nil, err
+		}
+
+		return helix.GetGlobalChatBadges()
+	})
+	if err != nil {
+		return
+	}
+
+	return found.(map[string]HelixChatBadge), nil
+}
@@ -0,0 +1,102 @@
+package retwitch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lrstanley/girc"
+)
+
+const replyTimeout = time.Duration(10) * time.Second
+
+func (c *Client) Say(channel string, text string) error {
+	return c.sendPrivmsg(channel, nil, text)
+}
+
+func (c *Client) Action(channel string, text string) error {
+	return c.sendPrivmsg(channel, nil, "\x01ACTION "+text+"\x01")
+}
+
+func (c *Client) Reply(channel string, parentID string, text string) (err error) {
+	tags := girc.Tags{}
+	if err = tags.Set("reply-parent-msg-id", parentID); err != nil {
+		return
+	}
+
+	return c.sendPrivmsg(channel, tags, text)
+}
+
+func (c *Client) ReplyTo(event *LiveEvent, text string) error {
+	return c.Reply(event.Channel, event.MessageID, text)
+}
+
+func (ch *ChannelInfo) Send(text string) error {
+	return ch.Client.Say(ch.Name, text)
+}
+
+func (ch *ChannelInfo) Action(text string) error {
+	return ch.Client.Action(ch.Name, text)
+}
+
+func (ch *ChannelInfo) Reply(parentID string, text string) error {
+	return ch.Client.Reply(ch.Name, parentID, text)
+}
+
+func (c *Client) sendPrivmsg(channel string, tags girc.Tags, text string) error {
+	if c.login == "" {
+		return ErrAnonymous
+	}
+
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	if err := c.requireScope(scopeChatEdit); err != nil {
+		return err
+	}
+
+	ctx := c.ctx
+	if err := c.limiter.waitChat(ctx, channel); err != nil {
+		return err
+	}
+
+	channel = "#" + channel
+
+	// Twitch only acknowledges a message with a USERSTATE or NOTICE that
+	// can't be tied back to it, so sends to a channel must not overlap.
+	lock := c.sendLock(channel)
+	lock.Lock()
+	defer lock.Unlock()
+
+	evch, cancel := c.waitIRC(channel, girc.NOTICE, "USERSTATE")
+	defer cancel()
+
+	c.irc.Send(ircTaggedEvent(tags, girc.PRIVMSG, channel, text))
+	result, err := c.waitReply(ctx, evch)
+	if err != nil {
+		return err
+	}
+
+	if result.Command == girc.NOTICE {
+		return ircToNoticeError(result)
+	}
+
+	return nil
+}
+
+func (c *Client) sendLock(channel string) *sync.Mutex {
+	c.sendmu.Lock()
+	defer c.sendmu.Unlock()
+
+	if c.sendLocks == nil {
+		c.sendLocks = map[string]*sync.Mutex{}
+	}
+
+	lock, ok := c.sendLocks[channel]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.sendLocks[channel] = lock
+	}
+
+	return lock
+}
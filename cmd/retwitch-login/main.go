@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tikatoo/retwitch"
+)
+
+func main() {
+	var app retwitch.OAuthApp
+	var scopes string
+	var outPath string
+	flag.StringVar(&app.ClientID, "client-id", os.Getenv("TWITCH_CLIENT_ID"), "twitch application client id")
+	flag.StringVar(&app.ClientSecret, "client-secret", os.Getenv("TWITCH_CLIENT_SECRET"), "twitch application client secret")
+	flag.StringVar(&app.RedirectURI, "redirect", "", "log in through the browser using this redirect uri, instead of a device code")
+	flag.StringVar(&scopes, "scopes", "chat:read chat:edit", "space-separated scopes to request")
+	flag.StringVar(&outPath, "o", "", "write the token to this file instead of stdout")
+	flag.Parse()
+
+	if app.ClientID == "" {
+		fmt.Fprintln(os.Stderr, "A client id is needed, from -client-id or TWITCH_CLIENT_ID")
+		os.Exit(2)
+	}
+
+	var token retwitch.Token
+	var err error
+	if app.RedirectURI != "" {
+		token, err = loginWithBrowser(app, strings.Fields(scopes))
+	} else {
+		token, err = loginWithDevice(app, strings.Fields(scopes))
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	out := os.Stdout
+	if outPath != "" {
+		out, err = os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		defer out.Close()
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&token); err != nil {
+		panic(err)
+	}
+}
+
+func loginWithDevice(app retwitch.OAuthApp, scopes []string) (token retwitch.Token, err error) {
+	login, err := app.StartDeviceLogin(scopes)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Go to %s and enter the code %s\n", login.VerificationURI, login.UserCode)
+	return app.WaitDeviceLogin(context.Background(), login)
+}
+
+func loginWithBrowser(app retwitch.OAuthApp, scopes []string) (token retwitch.Token, err error) {
+	redirect, err := url.Parse(app.RedirectURI)
+	if err != nil {
+		return
+	}
+
+	state, err := retwitch.NewOAuthState()
+	if err != nil {
+		return
+	}
+
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return
+	}
+
+	type callback struct {
+		code string
+		err  error
+	}
+
+	results := make(chan callback, 1)
+	mux := http.NewServeMux()
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			http.Error(w, "Login state doesn't match.", http.StatusBadRequest)
+			return
+		}
+
+		result := callback{code: q.Get("code")}
+		if q.Get("error") != "" {
+			result.err = fmt.Errorf("%s: %s", q.Get("error"), q.Get("error_description"))
+			fmt.Fprintln(w, "Login failed, you can close this page.")
+		} else {
+			fmt.Fprintln(w, "Logged in, you can close this page.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Fprintf(os.Stderr, "Open this page to log in:\n%s\n", app.AuthorizeURL(scopes, state))
+	result := <-results
+	if result.err != nil {
+		return token, result.err
+	}
+
+	return app.ExchangeCode(result.code)
+}
@@ -5,7 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"time"
+	"os/signal"
+	"strings"
 
 	"github.com/tikatoo/retwitch"
 )
@@ -13,23 +14,40 @@ import (
 func main() {
 	var useJSON bool
 	var showURLs bool
+	var cachePath string
 	flag.BoolVar(&useJSON, "json", false, "show json-formatted messages")
 	flag.BoolVar(&showURLs, "urls", false, "print image urls (text mode only)")
+	flag.StringVar(&cachePath, "cache", "", "keep helix lookups in this file between runs")
 	flag.Parse()
 	channels := flag.Args()
 
-	client, err := retwitch.NewAnonymousClient()
+	var config retwitch.ClientConfig
+	var cache *retwitch.FileCache
+	if cachePath != "" {
+		var err error
+		cache, err = retwitch.NewFileCache(cachePath)
+		if err != nil {
+			panic(err)
+		}
+
+		config.HelixCache = cache
+	}
+
+	client, err := retwitch.NewClient(config)
 	if err != nil {
 		panic(err)
 	}
 
-	for _, channel := range channels {
-		time.Sleep(500)
-		fmt.Println("Joining #" + channel)
-		err = client.Join(channel)
-		if err != nil {
-			panic(err)
-		}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		client.Close()
+	}()
+
+	fmt.Println("Joining #" + strings.Join(channels, ", #"))
+	for channel, err := range client.JoinMany(channels...) {
+		fmt.Printf("Couldn't join #%s: %s\n", channel, err)
 	}
 
 	if useJSON {
@@ -48,6 +66,12 @@ func main() {
 			}
 		}
 	}
+
+	if cache != nil {
+		if err := cache.Flush(); err != nil {
+			fmt.Println(err)
+		}
+	}
 }
 
 func printURLs(client *retwitch.Client, event retwitch.LiveEvent) {
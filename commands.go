@@ -0,0 +1,233 @@
+package retwitch
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const commandSweepInterval = time.Duration(1) * time.Minute
+
+type Permission int
+
+const (
+	PermEveryone Permission = iota
+	PermSubscriber
+	PermVIP
+	PermModerator
+	PermBroadcaster
+)
+
+type Command struct {
+	Name           string
+	Aliases        []string
+	Permission     Permission
+	UserCooldown   time.Duration
+	GlobalCooldown time.Duration
+	Handler        func(*CommandContext)
+}
+
+type CommandContext struct {
+	Client  *Client
+	Event   *LiveEvent
+	Command *Command
+	Name    string
+	Args    []Text
+}
+
+type CommandRouter struct {
+	Prefix string
+
+	mu        sync.Mutex
+	commands  map[string]*Command
+	cooldowns map[string]time.Time
+	swept     time.Time
+}
+
+func NewCommandRouter(prefix string) *CommandRouter {
+	return &CommandRouter{
+		Prefix:    prefix,
+		commands:  map[string]*Command{},
+		cooldowns: map[string]time.Time{},
+	}
+}
+
+func (r *CommandRouter) Register(cmd Command) error {
+	if cmd.Name == "" || cmd.Handler == nil {
+		return ErrInvalidCommand
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, exists := r.commands[strings.ToLower(name)]; exists {
+			return commandExistsError{name}
+		}
+	}
+
+	for _, name := range names {
+		r.commands[strings.ToLower(name)] = &cmd
+	}
+
+	return nil
+}
+
+func (r *CommandRouter) Attach(c *Client) (remove func()) {
+	return c.OnMessage(func(event *LiveEvent) {
+		r.Handle(c, event)
+	})
+}
+
+func (r *CommandRouter) Handle(c *Client, event *LiveEvent) bool {
+	args := splitArgs(event.Message)
+	if len(args) == 0 || args[0][0].EmoteID != "" {
+		return false
+	}
+
+	name := args[0].Plain()
+	if !strings.HasPrefix(name, r.Prefix) {
+		return false
+	}
+	name = strings.ToLower(strings.TrimPrefix(name, r.Prefix))
+
+	r.mu.Lock()
+	cmd, ok := r.commands[name]
+	if !ok || event.Sender.Permission() < cmd.Permission || !r.takeCooldown(cmd, event) {
+		r.mu.Unlock()
+		return false
+	}
+	r.mu.Unlock()
+
+	cmd.Handler(&CommandContext{
+		Client:  c,
+		Event:   event,
+		Command: cmd,
+		Name:    name,
+		Args:    args[1:],
+	})
+	return true
+}
+
+func (r *CommandRouter) takeCooldown(cmd *Command, event *LiveEvent) bool {
+	now := time.Now()
+	globalKey := event.Channel + "\x00" + cmd.Name
+	userKey := globalKey + "\x00" + event.Sender.User
+
+	if now.Before(r.cooldowns[globalKey]) || now.Before(r.cooldowns[userKey]) {
+		return false
+	}
+
+	if now.Sub(r.swept) >= commandSweepInterval {
+		r.sweepCooldowns(now)
+	}
+
+	if cmd.GlobalCooldown > 0 {
+		r.cooldowns[globalKey] = now.Add(cmd.GlobalCooldown)
+	}
+
+	if cmd.UserCooldown > 0 {
+		r.cooldowns[userKey] = now.Add(cmd.UserCooldown)
+	}
+
+	return true
+}
+
+func (r *CommandRouter) sweepCooldowns(now time.Time) {
+	for key, until := range r.cooldowns {
+		if !now.Before(until) {
+			delete(r.cooldowns, key)
+		}
+	}
+
+	r.swept = now
+}
+
+func (ctx *CommandContext) Reply(text string) error {
+	return ctx.Client.ReplyTo(ctx.Event, text)
+}
+
+func (ctx *CommandContext) Say(text string) error {
+	return ctx.Client.Say(ctx.Event.Channel, text)
+}
+
+func (ctx *CommandContext) ArgString() string {
+	words := make([]string, len(ctx.Args))
+	for i, arg := range ctx.Args {
+		words[i] = arg.Plain()
+	}
+
+	return strings.Join(words, " ")
+}
+
+func (v *Viewer) Permission() (perm Permission) {
+	for _, badge := range v.Badges {
+		var badgePerm Permission
+		switch strings.SplitN(badge, "/", 2)[0] {
+		case "broadcaster":
+			badgePerm = PermBroadcaster
+		case "moderator":
+			badgePerm = PermModerator
+		case "vip":
+			badgePerm = PermVIP
+		case "subscriber", "founder":
+			badgePerm = PermSubscriber
+		}
+
+		if badgePerm > perm {
+			perm = badgePerm
+		}
+	}
+
+	return
+}
+
+// Arguments are split on spaces, except inside double quotes, which group
+// words (emotes included) into a single argument.
+func splitArgs(message Text) (args []Text) {
+	var current Text
+	word := &strings.Builder{}
+	quoted := false
+
+	endWord := func() {
+		if word.Len() > 0 {
+			current = append(current, TextSegment{Text: word.String()})
+			word.Reset()
+		}
+	}
+
+	flush := func() {
+		endWord()
+		if len(current) > 0 {
+			args = append(args, current)
+			current = nil
+		}
+	}
+
+	for _, segment := range message {
+		for _, r := range segment.Text {
+			switch {
+			case r == '"' && quoted:
+				quoted = false
+			case r == '"' && word.Len() == 0 && len(current) == 0:
+				quoted = true
+			case unicode.IsSpace(r) && !quoted:
+				flush()
+			default:
+				word.WriteRune(r)
+			}
+		}
+
+		if segment.EmoteID != "" {
+			endWord()
+			emote := segment
+			emote.Text = ""
+			current = append(current, emote)
+		}
+	}
+
+	flush()
+	return
+}
@@ -0,0 +1,147 @@
+package retwitch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitArgs(t *testing.T) {
+	kappa := TextSegment{EmoteID: "25", EmoteText: "Kappa"}
+
+	tests := []struct {
+		name    string
+		message Text
+		want    []Text
+	}{
+		{
+			name:    "empty",
+			message: Text{},
+			want:    nil,
+		},
+		{
+			name:    "words",
+			message: Text{{Text: "!so  someone\telse "}},
+			want: []Text{
+				{{Text: "!so"}},
+				{{Text: "someone"}},
+				{{Text: "else"}},
+			},
+		},
+		{
+			name:    "quoted",
+			message: Text{{Text: `!addquote "hello there" friend`}},
+			want: []Text{
+				{{Text: "!addquote"}},
+				{{Text: "hello there"}},
+				{{Text: "friend"}},
+			},
+		},
+		{
+			name:    "unterminated quote",
+			message: Text{{Text: `!addquote "hello there`}},
+			want: []Text{
+				{{Text: "!addquote"}},
+				{{Text: "hello there"}},
+			},
+		},
+		{
+			name:    "empty quotes",
+			message: Text{{Text: `!addquote "" x`}},
+			want: []Text{
+				{{Text: "!addquote"}},
+				{{Text: "x"}},
+			},
+		},
+		{
+			name:    "quote inside a word",
+			message: Text{{Text: `!say it's "fine"`}},
+			want: []Text{
+				{{Text: "!say"}},
+				{{Text: "it's"}},
+				{{Text: "fine"}},
+			},
+		},
+		{
+			name:    "emote",
+			message: Text{{Text: "!hug ", EmoteID: kappa.EmoteID, EmoteText: kappa.EmoteText}, {Text: " back"}},
+			want: []Text{
+				{{Text: "!hug"}},
+				{kappa},
+				{{Text: "back"}},
+			},
+		},
+		{
+			name: "quoted emote",
+			message: Text{
+				{Text: `!addquote "so `, EmoteID: kappa.EmoteID, EmoteText: kappa.EmoteText},
+				{Text: ` much" done`},
+			},
+			want: []Text{
+				{{Text: "!addquote"}},
+				{{Text: "so "}, kappa, {Text: " much"}},
+				{{Text: "done"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := splitArgs(test.message)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitArgs(%q) = %#v, want %#v", test.message.Plain(), got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommandCooldowns(t *testing.T) {
+	r := NewCommandRouter("!")
+	cmd := &Command{Name: "hi", UserCooldown: time.Hour, GlobalCooldown: time.Millisecond}
+	event := func(user string) *LiveEvent {
+		return &LiveEvent{Channel: "chan", Sender: Viewer{User: user}}
+	}
+
+	if !r.takeCooldown(cmd, event("a")) {
+		t.Fatal("first use was refused")
+	}
+
+	if r.takeCooldown(cmd, event("b")) {
+		t.Error("global cooldown was ignored")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if r.takeCooldown(cmd, event("a")) {
+		t.Error("user cooldown was ignored")
+	}
+
+	if !r.takeCooldown(cmd, event("b")) {
+		t.Error("another user was refused after the global cooldown")
+	}
+
+	r.sweepCooldowns(time.Now().Add(time.Hour))
+	if len(r.cooldowns) != 0 {
+		t.Errorf("%d cooldowns left after sweeping", len(r.cooldowns))
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	r := NewCommandRouter("!")
+	handler := func(*CommandContext) {}
+
+	if err := r.Register(Command{Handler: handler}); err != ErrInvalidCommand {
+		t.Errorf("nameless command: got %v", err)
+	}
+
+	if err := r.Register(Command{Name: "hi"}); err != ErrInvalidCommand {
+		t.Errorf("command without a handler: got %v", err)
+	}
+
+	if err := r.Register(Command{Name: "hi", Handler: handler}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Register(Command{Name: "HI", Handler: handler}); err == nil {
+		t.Error("duplicate command was registered")
+	}
+}
@@ -1,23 +1,150 @@
 package retwitch
 
-import "github.com/lrstanley/girc"
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lrstanley/girc"
+)
+
+const (
+	connectTimeout    = time.Duration(30) * time.Second
+	quitTimeout       = time.Duration(2) * time.Second
+	reconnectMinDelay = time.Duration(1) * time.Second
+	reconnectMaxDelay = time.Duration(2) * time.Minute
+)
 
 type ClientConfig struct {
+	Username string
+	Token    string
+	ClientID string
+
+	Tokens       TokenSource
+	AppTokens    TokenSource
+	TokenInvalid func(error)
+
+	RateLimit      RateLimitPolicy
+	RateLimitQueue int
+
+	EventBuffer int
+	Overflow    OverflowPolicy
+
+	Handlers     HandlerConcurrency
+	HandlerPanic func(error)
+
+	CacheSize  int
+	CacheTTL   time.Duration
+	HelixCache HelixCache
+
+	HelixRetries int
+
+	Endpoints Endpoints
+	Transport http.RoundTripper
+	Dialer    Dialer
 }
 
 func NewClient(config ClientConfig) (c *Client, err error) {
-	c = &Client{}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	return NewClientContext(ctx, config)
+}
+
+// OAuthApp is the app the client authenticates as, with the configured
+// auth endpoint and transport, for building token sources that match it.
+func (config ClientConfig) OAuthApp() (app OAuthApp) {
+	app = OAuthApp{
+		ClientID:   config.ClientID,
+		AuthURL:    config.Endpoints.withDefaults().Auth,
+		HTTPClient: httpClientFor(config.Transport),
+	}
+	if app.ClientID == "" {
+		app.ClientID = os.Getenv("TWITCH_CLIENT_ID")
+	}
 
-	c.irc, err = ircConnect("", "")
+	return
+}
+
+func NewClientContext(ctx context.Context, config ClientConfig) (c *Client, err error) {
+	endpoints := config.Endpoints.withDefaults()
+	ircHost, ircPort, ircSSL, err := ircServer(endpoints.IRC)
 	if err != nil {
-		c = nil
-		return
+		return nil, err
+	}
+
+	c = &Client{
+		login:   config.Username,
+		tokens:  config.Tokens,
+		limiter: newRateLimiter(config.RateLimit, config.RateLimitQueue),
+		joined:  map[string]struct{}{},
+		stopped: make(chan struct{}),
+
+		appTokens:    config.AppTokens,
+		tokenInvalid: config.TokenInvalid,
+		helixCache:   config.HelixCache,
+		helixRetries: config.HelixRetries,
+		helixURL:     endpoints.Helix,
+		transport:    config.Transport,
+		dialer:       config.Dialer,
+	}
+
+	c.oauth = config.OAuthApp()
+
+	if c.tokens == nil && config.Token != "" {
+		c.tokens = NewStaticTokenSource(c.oauth.ClientID, config.Token)
+	}
+
+	if c.login == "" && c.tokens != nil {
+		if err = c.resolveLogin(ctx); err != nil {
+			return nil, err
+		}
 	}
 
-	c.levs = make(chan LiveEvent, 24)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.events = c.subscribe(SubscribeOptions{
+		Buffer:   config.EventBuffer,
+		Overflow: config.Overflow,
+	})
+	c.events.stream.holdUntilClaimed()
+
+	c.dispatcher = &dispatcher{
+		client:  c,
+		mode:    config.Handlers,
+		onPanic: config.HandlerPanic,
+		workers: map[string]*liveStream{},
+	}
+
+	c.irc = ircNew(ircHost, ircPort, ircSSL, c.login)
 	c.irc.Handlers.Add(girc.PRIVMSG, c.onPrivmsg)
+	c.irc.Handlers.Add("WHISPER", c.onWhisper)
+	c.irc.Handlers.Add("USERNOTICE", c.onUsernotice)
+	c.irc.Handlers.Add("USERSTATE", c.onUserstate)
+	c.irc.Handlers.Add("ROOMSTATE", c.onRoomstate)
+	c.irc.Handlers.Add("CLEARCHAT", c.onClearchat)
+	c.irc.Handlers.Add("CLEARMSG", c.onClearmsg)
+	c.irc.Handlers.Add(girc.RPL_WELCOME, c.onWelcome)
+	c.irc.Handlers.Add(girc.NOTICE, c.onNotice)
+	c.irc.Handlers.Add("RECONNECT", c.onReconnect)
+
+	c.channels = newLRUCache(cacheSize(config.CacheSize), 0)
+	c.pinned = map[string]*ChannelInfo{}
+	c.assets = newLRUCache(2*cacheSize(config.CacheSize)+1, cacheTTL(config.CacheTTL))
 
-	c.channels = map[string]*ChannelInfo{}
+	if err = c.logIn(ctx); err != nil {
+		c.cancel()
+		c.closeStreams()
+		c = nil
+		return
+	}
+
+	go c.supervise()
+	if c.tokens != nil {
+		go c.validateTokens()
+	}
 
 	return
 }
@@ -25,3 +152,228 @@ func NewClient(config ClientConfig) (c *Client, err error) {
 func NewAnonymousClient() (client *Client, err error) {
 	return NewClient(ClientConfig{})
 }
+
+func (c *Client) logIn(ctx context.Context) (err error) {
+	if c.tokens == nil {
+		return c.dial(ctx)
+	}
+
+	token, err := c.tokens.Token()
+	if err != nil {
+		return
+	}
+
+	c.irc.Config.ServerPass = ircPassword(token.AccessToken)
+	if err = c.dial(ctx); !errors.Is(err, ErrLoginFailed) {
+		return
+	}
+
+	// Twitch reports an expired or revoked token as a failed login, so give
+	// the source one chance to replace it.
+	if token, refreshErr := c.tokens.Refresh(token); refreshErr == nil {
+		c.irc.Config.ServerPass = ircPassword(token.AccessToken)
+		err = c.dial(ctx)
+	}
+
+	return
+}
+
+func (c *Client) dial(ctx context.Context) (err error) {
+	welcome := make(chan error, 1)
+	lost := make(chan error, 1)
+
+	c.connmu.Lock()
+	settled := c.settled
+	c.welcome = welcome
+	c.lost = lost
+	c.connmu.Unlock()
+
+	if settled != nil {
+		<-settled
+	}
+
+	go func() {
+		if c.dialer != nil {
+			lost <- c.irc.DialerConnect(c.dialer)
+		} else {
+			lost <- c.irc.Connect()
+		}
+	}()
+
+	select {
+	case err = <-welcome:
+		if err != nil {
+			c.irc.Close()
+			<-lost
+		}
+	case err = <-lost:
+		if err == nil {
+			err = ErrDisconnected
+		}
+	case <-ctx.Done():
+		err = contextError(ctx)
+
+		settled := make(chan struct{})
+		c.connmu.Lock()
+		c.welcome = nil
+		c.settled = settled
+		c.connmu.Unlock()
+
+		go c.abandon(lost, settled)
+	}
+
+	return
+}
+
+func (c *Client) abandon(lost <-chan error, settled chan<- struct{}) {
+	defer close(settled)
+
+	// Close does nothing until Connect has a connection to close, so keep
+	// trying until the abandoned attempt has actually finished.
+	for {
+		c.irc.Close()
+		select {
+		case <-lost:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *Client) signalWelcome(err error) {
+	c.connmu.Lock()
+	defer c.connmu.Unlock()
+
+	if c.welcome != nil {
+		c.welcome <- err
+		c.welcome = nil
+	}
+}
+
+func (c *Client) supervise() {
+	defer close(c.stopped)
+
+	for {
+		c.connmu.Lock()
+		lost := c.lost
+		c.connmu.Unlock()
+
+		var err error
+		select {
+		case err = <-lost:
+		case <-c.ctx.Done():
+			c.hangUp(lost)
+			return
+		}
+
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			err = ErrDisconnected
+		}
+
+		c.emit(LiveEvent{
+			Time:    time.Now(),
+			Kind:    DisconnectedEvent,
+			Message: Text{{Text: err.Error()}},
+		})
+
+		for attempt := 0; ; attempt++ {
+			delay := reconnectDelay(attempt)
+			c.emit(LiveEvent{
+				Time:    time.Now(),
+				Kind:    ReconnectingEvent,
+				Message: Text{{Text: "retrying in " + delay.String()}},
+			})
+
+			select {
+			case <-time.After(delay):
+			case <-c.ctx.Done():
+				return
+			}
+
+			if err = c.redial(); err == nil {
+				break
+			}
+
+			if c.ctx.Err() != nil {
+				c.connmu.Lock()
+				settled := c.settled
+				c.connmu.Unlock()
+
+				if settled != nil {
+					<-settled
+				}
+				return
+			}
+		}
+
+		c.rejoin()
+		c.emit(LiveEvent{Time: time.Now(), Kind: ResumedEvent})
+	}
+}
+
+func (c *Client) hangUp(lost <-chan error) {
+	c.irc.Quit("")
+
+	select {
+	case <-lost:
+	case <-time.After(quitTimeout):
+		c.irc.Close()
+		<-lost
+	}
+}
+
+func (c *Client) redial() error {
+	ctx, cancel := context.WithTimeout(c.ctx, connectTimeout)
+	defer cancel()
+	return c.logIn(ctx)
+}
+
+func (c *Client) rejoin() {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, channel := range c.joinedChannels() {
+		if c.limiter.waitRejoin(c.ctx) != nil {
+			return
+		}
+
+		wg.Add(1)
+		go func(channel string) {
+			defer wg.Done()
+
+			err := c.confirmJoin(c.ctx, channel)
+			if err == nil || c.ctx.Err() != nil {
+				return
+			}
+
+			c.Part(channel)
+			c.emit(LiveEvent{
+				Time:    time.Now(),
+				Channel: channel,
+				Kind:    RejoinFailedEvent,
+				Message: Text{{Text: err.Error()}},
+			})
+		}(channel)
+	}
+}
+
+func reconnectDelay(attempt int) time.Duration {
+	return backoffDelay(attempt, reconnectMinDelay, reconnectMaxDelay)
+}
+
+func backoffDelay(attempt int, min time.Duration, max time.Duration) time.Duration {
+	delay := min
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
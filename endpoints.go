@@ -0,0 +1,75 @@
+package retwitch
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type Endpoints struct {
+	Helix string
+	Auth  string
+	IRC   string
+}
+
+var DefaultEndpoints = Endpoints{
+	Helix: "https://api.twitch.tv/helix",
+	Auth:  "https://id.twitch.tv/oauth2",
+	IRC:   "ircs://irc.chat.twitch.tv:6697",
+}
+
+type Dialer interface {
+	Dial(network string, address string) (net.Conn, error)
+}
+
+func (e Endpoints) withDefaults() Endpoints {
+	if e.Helix == "" {
+		e.Helix = DefaultEndpoints.Helix
+	}
+
+	if e.Auth == "" {
+		e.Auth = DefaultEndpoints.Auth
+	}
+
+	if e.IRC == "" {
+		e.IRC = DefaultEndpoints.IRC
+	}
+
+	return e
+}
+
+func ircServer(endpoint string) (host string, port int, ssl bool, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return
+	}
+
+	switch u.Scheme {
+	case "ircs":
+		ssl, port = true, 6697
+	case "irc":
+		ssl, port = false, 6667
+	default:
+		err = errIRCScheme
+		return
+	}
+
+	host = u.Hostname()
+	if u.Port() != "" {
+		port, err = strconv.Atoi(u.Port())
+	}
+
+	return
+}
+
+func httpClientFor(rt http.RoundTripper) *http.Client {
+	if rt == nil {
+		return http.DefaultClient
+	}
+
+	return &http.Client{Transport: rt}
+}
+
+var errIRCScheme = errors.New("irc endpoint must be an irc:// or ircs:// url")
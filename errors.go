@@ -2,11 +2,58 @@ package retwitch
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 )
 
 var ErrNoSuchBadge = errors.New("no such badge")
+var ErrNoSuchUser = errors.New("no such user")
 var ErrHTTPStatus = errors.New("http response error")
+var ErrLoginFailed = errors.New("login failed")
+var ErrAnonymous = errors.New("anonymous clients can't send messages")
+var ErrDisconnected = errors.New("disconnected from twitch")
+var ErrClosed = errors.New("client is closed")
+var ErrTimeout = errors.New("timed out waiting for twitch")
+var ErrHandlerPanic = errors.New("event handler panicked")
+var ErrCommandExists = errors.New("command already registered")
+var ErrInvalidCommand = errors.New("command needs a name and a handler")
+var ErrTokenNotRenewable = errors.New("token can't be renewed")
+var ErrAuthorization = errors.New("twitch authorization failed")
+var ErrTokenInvalid = errors.New("token is invalid or revoked")
+var ErrMissingScope = errors.New("token is missing a required scope")
+
+var (
+	ErrNotice               = errors.New("twitch refused the message")
+	ErrSlowMode             = errors.New("channel is in slow mode")
+	ErrFollowersOnly        = errors.New("channel is in followers-only mode")
+	ErrSubsOnly             = errors.New("channel is in subscribers-only mode")
+	ErrEmoteOnly            = errors.New("channel is in emote-only mode")
+	ErrUniqueChat           = errors.New("channel is in unique-chat mode")
+	ErrDuplicateMessage     = errors.New("duplicate message")
+	ErrBanned               = errors.New("banned from channel")
+	ErrTimedOut             = errors.New("timed out in channel")
+	ErrRateLimited          = errors.New("sending messages too quickly")
+	ErrChannelSuspended     = errors.New("channel is suspended")
+	ErrVerificationRequired = errors.New("account verification required")
+)
+
+var noticeErrors = map[string]error{
+	"msg_slowmode":                       ErrSlowMode,
+	"msg_followersonly":                  ErrFollowersOnly,
+	"msg_followersonly_followed":         ErrFollowersOnly,
+	"msg_followersonly_zero":             ErrFollowersOnly,
+	"msg_subsonly":                       ErrSubsOnly,
+	"msg_emoteonly":                      ErrEmoteOnly,
+	"msg_r9k":                            ErrUniqueChat,
+	"msg_duplicate":                      ErrDuplicateMessage,
+	"msg_banned":                         ErrBanned,
+	"msg_banned_email_alias":             ErrBanned,
+	"msg_timedout":                       ErrTimedOut,
+	"msg_ratelimit":                      ErrRateLimited,
+	"msg_channel_suspended":              ErrChannelSuspended,
+	"msg_verified_email":                 ErrVerificationRequired,
+	"msg_requires_verified_phone_number": ErrVerificationRequired,
+}
 
 type httpStatusError struct {
 	*http.Response
@@ -19,3 +66,84 @@ func (e httpStatusError) Error() string {
 func (e httpStatusError) Unwrap() error {
 	return ErrHTTPStatus
 }
+
+type oauthError struct {
+	Status  int
+	Message string
+}
+
+func (e oauthError) Error() string {
+	return "twitch authorization failed: " + e.Message
+}
+
+func (e oauthError) Unwrap() error {
+	return ErrAuthorization
+}
+
+type missingScopeError struct {
+	Scope string
+}
+
+func (e missingScopeError) Error() string {
+	return "token is missing the " + e.Scope + " scope"
+}
+
+func (e missingScopeError) Unwrap() error {
+	return ErrMissingScope
+}
+
+type loginError struct {
+	Message string
+}
+
+func (e loginError) Error() string {
+	return "twitch login failed: " + e.Message
+}
+
+func (e loginError) Unwrap() error {
+	return ErrLoginFailed
+}
+
+type noticeError struct {
+	Channel string
+	MsgID   string
+	Message string
+}
+
+func (e noticeError) Error() string {
+	return "#" + e.Channel + ": " + e.Message
+}
+
+func (e noticeError) Unwrap() error {
+	if err, ok := noticeErrors[e.MsgID]; ok {
+		return err
+	}
+
+	return ErrNotice
+}
+
+type handlerPanic struct {
+	Event LiveEvent
+	Value interface{}
+	Stack []byte
+}
+
+func (e handlerPanic) Error() string {
+	return fmt.Sprintf("handler panicked on %s: %v", e.Event.String(), e.Value)
+}
+
+func (e handlerPanic) Unwrap() error {
+	return ErrHandlerPanic
+}
+
+type commandExistsError struct {
+	Name string
+}
+
+func (e commandExistsError) Error() string {
+	return "command " + e.Name + " is already registered"
+}
+
+func (e commandExistsError) Unwrap() error {
+	return ErrCommandExists
+}
@@ -13,22 +13,93 @@ type LiveEventKind int
 const (
 	MessageEvent LiveEventKind = iota
 	ActionEvent
+	DisconnectedEvent
+	ReconnectingEvent
+	ResumedEvent
+	SubEvent
+	ResubEvent
+	SubGiftEvent
+	MysteryGiftEvent
+	RaidEvent
+	AnnouncementEvent
+	BitsBadgeTierEvent
+	ViewerMilestoneEvent
+	BanEvent
+	TimeoutEvent
+	ClearChatEvent
+	DeleteMessageEvent
+	RoomStateEvent
+	WhisperEvent
+	RejoinFailedEvent
 )
 
+var liveEventKindWords = map[LiveEventKind]string{
+	MessageEvent:      "message",
+	ActionEvent:       "action",
+	DisconnectedEvent: "disconnected",
+	ReconnectingEvent: "reconnecting",
+	ResumedEvent:      "resumed",
+
+	SubEvent:             "sub",
+	ResubEvent:           "resub",
+	SubGiftEvent:         "subgift",
+	MysteryGiftEvent:     "submysterygift",
+	RaidEvent:            "raid",
+	AnnouncementEvent:    "announcement",
+	BitsBadgeTierEvent:   "bitsbadgetier",
+	ViewerMilestoneEvent: "viewermilestone",
+
+	BanEvent:           "ban",
+	TimeoutEvent:       "timeout",
+	ClearChatEvent:     "clearchat",
+	DeleteMessageEvent: "deletemessage",
+
+	RoomStateEvent: "roomstate",
+	WhisperEvent:   "whisper",
+
+	RejoinFailedEvent: "rejoinfailed",
+}
+
 type Viewer struct {
-	User    string   `json:"user"`
-	Display string   `json:"display,omitempty"`
-	Color   string   `json:"color,omitempty"`
-	Badges  []string `json:"badges,omitempty"`
+	User       string   `json:"user"`
+	ID         string   `json:"id,omitempty"`
+	Display    string   `json:"display,omitempty"`
+	Color      string   `json:"color,omitempty"`
+	Badges     []string `json:"badges,omitempty"`
+	BadgeInfo  []string `json:"badge_info,omitempty"`
+	SubMonths  int      `json:"sub_months,omitempty"`
+	Mod        bool     `json:"mod,omitempty"`
+	Subscriber bool     `json:"subscriber,omitempty"`
+	VIP        bool     `json:"vip,omitempty"`
+	Turbo      bool     `json:"turbo,omitempty"`
+}
+
+type ReplyParent struct {
+	MessageID       string `json:"id"`
+	Sender          Viewer `json:"sender"`
+	Body            string `json:"body,omitempty"`
+	ThreadMessageID string `json:"thread_id,omitempty"`
+	ThreadUser      string `json:"thread_user,omitempty"`
 }
 
 type LiveEvent struct {
 	MessageID string        `json:"id,omitempty"`
 	Time      time.Time     `json:"time"`
 	Channel   string        `json:"channel"`
+	RoomID    string        `json:"room_id,omitempty"`
 	Sender    Viewer        `json:"sender"`
 	Kind      LiveEventKind `json:"kind"`
 	Message   Text          `json:"message"`
+	Bits      int           `json:"bits,omitempty"`
+	Nonce     string        `json:"nonce,omitempty"`
+	Reply     *ReplyParent  `json:"reply,omitempty"`
+	Notice    *UserNotice   `json:"notice,omitempty"`
+
+	FirstMessage     bool `json:"first_message,omitempty"`
+	ReturningChatter bool `json:"returning_chatter,omitempty"`
+
+	Moderation *Moderation `json:"moderation,omitempty"`
+	Room       *RoomState  `json:"room,omitempty"`
 }
 
 func (v *Viewer) String() string {
@@ -59,8 +130,10 @@ func (e *LiveEvent) String() string {
 	b.Grow(16 + len(e.Channel) + len(sender) + len(message))
 	b.WriteString("[")
 	b.WriteString(e.Time.Format("15:04"))
-	b.WriteString(" in ")
-	b.WriteString(e.Channel)
+	if e.Channel != "" {
+		b.WriteString(" in ")
+		b.WriteString(e.Channel)
+	}
 	b.WriteString("] ")
 
 	switch e.Kind {
@@ -73,6 +146,56 @@ func (e *LiveEvent) String() string {
 		b.WriteString(sender)
 		b.WriteString(" ")
 		b.WriteString(message)
+	case WhisperEvent:
+		b.WriteString(sender)
+		b.WriteString(" whispers: ")
+		b.WriteString(message)
+	case DisconnectedEvent, ReconnectingEvent, ResumedEvent, RejoinFailedEvent:
+		b.WriteString("<")
+		b.WriteString(liveEventKindWords[e.Kind])
+		if message != "" {
+			b.WriteString(": ")
+			b.WriteString(message)
+		}
+		b.WriteString(">")
+	case SubEvent, ResubEvent, SubGiftEvent, MysteryGiftEvent, RaidEvent,
+		AnnouncementEvent, BitsBadgeTierEvent, ViewerMilestoneEvent:
+		b.WriteString("<")
+		b.WriteString(liveEventKindWords[e.Kind])
+		b.WriteString("> ")
+		if e.Notice != nil && e.Notice.SystemMessage != "" {
+			b.WriteString(e.Notice.SystemMessage)
+		} else {
+			b.WriteString(sender)
+		}
+		if message != "" {
+			b.WriteString(": ")
+			b.WriteString(message)
+		}
+	case BanEvent, TimeoutEvent, ClearChatEvent, DeleteMessageEvent:
+		b.WriteString("<")
+		b.WriteString(liveEventKindWords[e.Kind])
+		if e.Moderation != nil && e.Moderation.Duration != 0 {
+			b.WriteString(" ")
+			b.WriteString(e.Moderation.Duration.String())
+		}
+		b.WriteString(">")
+		if e.Moderation != nil && e.Moderation.Target != nil {
+			b.WriteString(" ")
+			b.WriteString(e.Moderation.Target.String())
+		}
+		if message != "" {
+			b.WriteString(": ")
+			b.WriteString(message)
+		}
+	case RoomStateEvent:
+		b.WriteString("<")
+		b.WriteString(liveEventKindWords[e.Kind])
+		if e.Room != nil {
+			b.WriteString(" ")
+			b.WriteString(e.Room.String())
+		}
+		b.WriteString(">")
 	default:
 		b.WriteString("<unknown event ")
 		b.WriteString(strconv.Itoa(int(e.Kind)))
@@ -84,15 +207,34 @@ func (e *LiveEvent) String() string {
 	return b.String()
 }
 
-func (k LiveEventKind) MarshalJSON() (result []byte, err error) {
-	var word string
+func (r *RoomState) String() string {
+	modes := []string{}
+	if r.EmoteOnly {
+		modes = append(modes, "emote-only")
+	}
+	if r.FollowersOnly {
+		modes = append(modes, "followers-only="+strconv.Itoa(r.FollowerMinutes)+"m")
+	}
+	if r.R9K {
+		modes = append(modes, "r9k")
+	}
+	if r.SlowSeconds > 0 {
+		modes = append(modes, "slow="+strconv.Itoa(r.SlowSeconds)+"s")
+	}
+	if r.SubsOnly {
+		modes = append(modes, "subs-only")
+	}
 
-	switch k {
-	case MessageEvent:
-		word = "message"
-	case ActionEvent:
-		word = "action"
-	default:
+	if len(modes) == 0 {
+		return "open"
+	}
+
+	return strings.Join(modes, ", ")
+}
+
+func (k LiveEventKind) MarshalJSON() (result []byte, err error) {
+	word, ok := liveEventKindWords[k]
+	if !ok {
 		return nil, errEventKind
 	}
 
@@ -105,16 +247,14 @@ func (k *LiveEventKind) UnmarshalJSON(data []byte) (err error) {
 		return
 	}
 
-	switch word {
-	case "message":
-		*k = MessageEvent
-	case "action":
-		*k = ActionEvent
-	default:
-		err = errEventKind
+	for kind, kindWord := range liveEventKindWords {
+		if kindWord == word {
+			*k = kind
+			return
+		}
 	}
 
-	return
+	return errEventKind
 }
 
 var errEventKind = errors.New("invalid event kind")
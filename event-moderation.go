@@ -0,0 +1,74 @@
+package retwitch
+
+import (
+	"time"
+
+	"github.com/lrstanley/girc"
+)
+
+type Moderation struct {
+	Target          *Viewer       `json:"target,omitempty"`
+	TargetMessageID string        `json:"target_message_id,omitempty"`
+	Duration        time.Duration `json:"duration,omitempty"`
+}
+
+func ircToClearChatEvent(ircEvent girc.Event) (event LiveEvent) {
+	event = LiveEvent{
+		Time:       ircTime(ircEvent),
+		Channel:    ircEvent.Params[0][1:],
+		Kind:       ClearChatEvent,
+		Moderation: &Moderation{},
+	}
+
+	if len(ircEvent.Params) < 2 {
+		return
+	}
+
+	event.Kind = BanEvent
+	event.Moderation.Target = &Viewer{
+		User: ircEvent.Last(),
+		ID:   tagString(ircEvent.Tags, "target-user-id"),
+	}
+
+	if seconds := tagInt(ircEvent.Tags, "ban-duration"); seconds > 0 {
+		event.Kind = TimeoutEvent
+		event.Moderation.Duration = time.Duration(seconds) * time.Second
+	}
+
+	return
+}
+
+func ircToClearMsgEvent(ircEvent girc.Event) (event LiveEvent) {
+	event = LiveEvent{
+		Time:    ircTime(ircEvent),
+		Channel: ircEvent.Params[0][1:],
+		Kind:    DeleteMessageEvent,
+		Moderation: &Moderation{
+			Target:          &Viewer{User: tagString(ircEvent.Tags, "login")},
+			TargetMessageID: tagString(ircEvent.Tags, "target-msg-id"),
+		},
+	}
+
+	if len(ircEvent.Params) > 1 {
+		event.Message = Text{{Text: ircEvent.Last()}}
+	}
+
+	return
+}
+
+func (e *LiveEvent) Retracts(other *LiveEvent) bool {
+	if e.Moderation == nil || e.Channel != other.Channel || other.Time.After(e.Time) {
+		return false
+	}
+
+	switch e.Kind {
+	case ClearChatEvent:
+		return true
+	case BanEvent, TimeoutEvent:
+		return e.Moderation.Target != nil && e.Moderation.Target.User == other.Sender.User
+	case DeleteMessageEvent:
+		return other.MessageID != "" && e.Moderation.TargetMessageID == other.MessageID
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package retwitch
+
+import (
+	"strconv"
+
+	"github.com/lrstanley/girc"
+)
+
+type UserNotice struct {
+	SystemMessage string  `json:"system_message,omitempty"`
+	Plan          string  `json:"plan,omitempty"`
+	PlanName      string  `json:"plan_name,omitempty"`
+	Months        int     `json:"months,omitempty"`
+	StreakMonths  int     `json:"streak_months,omitempty"`
+	GiftMonths    int     `json:"gift_months,omitempty"`
+	Recipient     *Viewer `json:"recipient,omitempty"`
+	GiftCount     int     `json:"gift_count,omitempty"`
+	GiftTotal     int     `json:"gift_total,omitempty"`
+	ViewerCount   int     `json:"viewer_count,omitempty"`
+	Color         string  `json:"color,omitempty"`
+	Threshold     int     `json:"threshold,omitempty"`
+	Category      string  `json:"category,omitempty"`
+	Value         int     `json:"value,omitempty"`
+}
+
+var userNoticeKinds = map[string]LiveEventKind{
+	"sub":                SubEvent,
+	"resub":              ResubEvent,
+	"subgift":            SubGiftEvent,
+	"anonsubgift":        SubGiftEvent,
+	"submysterygift":     MysteryGiftEvent,
+	"anonsubmysterygift": MysteryGiftEvent,
+	"raid":               RaidEvent,
+	"announcement":       AnnouncementEvent,
+	"bitsbadgetier":      BitsBadgeTierEvent,
+	"viewermilestone":    ViewerMilestoneEvent,
+}
+
+func ircToUserNoticeEvent(ch *ChannelInfo, ircEvent girc.Event) (event LiveEvent, ok bool) {
+	msgid, _ := ircEvent.Tags.Get("msg-id")
+	kind, ok := userNoticeKinds[msgid]
+	if !ok {
+		return
+	}
+
+	event = LiveEvent{
+		MessageID: tagString(ircEvent.Tags, "id"),
+		Time:      ircTime(ircEvent),
+		Channel:   ircEvent.Params[0][1:],
+		RoomID:    tagString(ircEvent.Tags, "room-id"),
+		Sender:    ircToSender(ircEvent),
+		Kind:      kind,
+		Notice:    ircToUserNotice(ircEvent),
+	}
+
+	if len(ircEvent.Params) > 1 {
+		event.Message = ircToMessage(ch, ircEvent)
+	}
+
+	return
+}
+
+func ircToUserNotice(ircEvent girc.Event) *UserNotice {
+	tags := ircEvent.Tags
+	notice := &UserNotice{
+		Plan:         tagString(tags, "msg-param-sub-plan"),
+		PlanName:     tagString(tags, "msg-param-sub-plan-name"),
+		Months:       tagInt(tags, "msg-param-cumulative-months"),
+		StreakMonths: tagInt(tags, "msg-param-streak-months"),
+		GiftMonths:   tagInt(tags, "msg-param-gift-months"),
+		GiftCount:    tagInt(tags, "msg-param-mass-gift-count"),
+		GiftTotal:    tagInt(tags, "msg-param-sender-count"),
+		ViewerCount:  tagInt(tags, "msg-param-viewerCount"),
+		Color:        tagString(tags, "msg-param-color"),
+		Threshold:    tagInt(tags, "msg-param-threshold"),
+		Category:     tagString(tags, "msg-param-category"),
+		Value:        tagInt(tags, "msg-param-value"),
+	}
+
+	notice.SystemMessage, _ = tags.Get("system-msg")
+
+	if notice.Months == 0 {
+		notice.Months = tagInt(tags, "msg-param-months")
+	}
+
+	if recipient := tagString(tags, "msg-param-recipient-user-name"); recipient != "" {
+		notice.Recipient = &Viewer{
+			User: recipient,
+			ID:   tagString(tags, "msg-param-recipient-id"),
+		}
+		if display := tagString(tags, "msg-param-recipient-display-name"); display != recipient {
+			notice.Recipient.Display = display
+		}
+	}
+
+	return notice
+}
+
+func tagString(tags girc.Tags, key string) string {
+	value, _ := tags.Get(key)
+	return value
+}
+
+func tagInt(tags girc.Tags, key string) int {
+	value, err := strconv.Atoi(tagString(tags, key))
+	if err != nil {
+		return 0
+	}
+
+	return value
+}
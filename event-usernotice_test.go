@@ -0,0 +1,187 @@
+package retwitch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lrstanley/girc"
+)
+
+func TestUserNoticeEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		ok     bool
+		kind   LiveEventKind
+		text   Text
+		notice UserNotice
+	}{
+		{
+			name: "sub",
+			line: `@badges=subscriber/0;display-name=Alice;id=n1;login=alice;msg-id=sub;msg-param-cumulative-months=1;msg-param-sub-plan=1000;msg-param-sub-plan-name=Channel\sSub;room-id=42;system-msg=alice\ssubscribed\sat\sTier\s1.;tmi-sent-ts=1700000000000;user-id=7 :tmi.twitch.tv USERNOTICE #chan`,
+			ok:   true,
+			kind: SubEvent,
+			notice: UserNotice{
+				SystemMessage: "alice subscribed at Tier 1.",
+				Plan:          "1000",
+				PlanName:      "Channel Sub",
+				Months:        1,
+			},
+		},
+		{
+			name: "resub",
+			line: `@badges=subscriber/12;display-name=bob;id=n2;login=bob;msg-id=resub;msg-param-months=13;msg-param-streak-months=4;msg-param-sub-plan=Prime;room-id=42;system-msg=bob\sresubscribed;tmi-sent-ts=1700000000001;user-id=8 :tmi.twitch.tv USERNOTICE #chan :still here`,
+			ok:   true,
+			kind: ResubEvent,
+			text: Text{{Text: "still here"}},
+			notice: UserNotice{
+				SystemMessage: "bob resubscribed",
+				Plan:          "Prime",
+				Months:        13,
+				StreakMonths:  4,
+			},
+		},
+		{
+			name: "subgift",
+			line: `@display-name=carol;id=n3;login=carol;msg-id=subgift;msg-param-gift-months=3;msg-param-months=2;msg-param-recipient-display-name=Dave;msg-param-recipient-id=9;msg-param-recipient-user-name=dave;msg-param-sender-count=5;msg-param-sub-plan=2000;room-id=42;tmi-sent-ts=1700000000002;user-id=10 :tmi.twitch.tv USERNOTICE #chan`,
+			ok:   true,
+			kind: SubGiftEvent,
+			notice: UserNotice{
+				Plan:       "2000",
+				Months:     2,
+				GiftMonths: 3,
+				GiftTotal:  5,
+				Recipient:  &Viewer{User: "dave", ID: "9", Display: "Dave"},
+			},
+		},
+		{
+			name: "raid",
+			line: `@display-name=Erin;id=n4;login=erin;msg-id=raid;msg-param-viewerCount=250;room-id=42;system-msg=250\sraiders\sfrom\sErin;tmi-sent-ts=1700000000003;user-id=11 :tmi.twitch.tv USERNOTICE #chan`,
+			ok:   true,
+			kind: RaidEvent,
+			notice: UserNotice{
+				SystemMessage: "250 raiders from Erin",
+				ViewerCount:   250,
+			},
+		},
+		{
+			name: "announcement",
+			line: `@badges=moderator/1;display-name=frank;id=n5;login=frank;mod=1;msg-id=announcement;msg-param-color=PRIMARY;room-id=42;tmi-sent-ts=1700000000004;user-id=12 :tmi.twitch.tv USERNOTICE #chan :listen up`,
+			ok:   true,
+			kind: AnnouncementEvent,
+			text: Text{{Text: "listen up"}},
+			notice: UserNotice{
+				Color: "PRIMARY",
+			},
+		},
+		{
+			name: "unknown msg-id",
+			line: `@id=n6;login=gina;msg-id=somethingnew;room-id=42;tmi-sent-ts=1700000000005 :tmi.twitch.tv USERNOTICE #chan`,
+			ok:   false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ircEvent := girc.ParseEvent(test.line)
+			if ircEvent == nil {
+				t.Fatal("unparseable line")
+			}
+
+			event, ok := ircToUserNoticeEvent(nil, *ircEvent)
+			if ok != test.ok {
+				t.Fatalf("ok = %v, want %v", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+
+			if event.Kind != test.kind {
+				t.Errorf("kind = %v, want %v", event.Kind, test.kind)
+			}
+			if event.Channel != "chan" || event.RoomID != "42" {
+				t.Errorf("channel = %q, room = %q", event.Channel, event.RoomID)
+			}
+			if !reflect.DeepEqual(event.Message, test.text) {
+				t.Errorf("message = %#v, want %#v", event.Message, test.text)
+			}
+			if event.Notice == nil || !reflect.DeepEqual(*event.Notice, test.notice) {
+				t.Errorf("notice = %#v, want %#v", event.Notice, test.notice)
+			}
+
+			enc, err := json.Marshal(&event)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var decoded LiveEvent
+			if err = json.Unmarshal(enc, &decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			if !decoded.Time.Equal(event.Time) {
+				t.Errorf("time = %v, want %v", decoded.Time, event.Time)
+			}
+			decoded.Time = event.Time
+
+			// A missing message is written as [], which reads back empty
+			// rather than nil.
+			if len(decoded.Message) == 0 && len(event.Message) == 0 {
+				decoded.Message = event.Message
+			}
+
+			if !reflect.DeepEqual(decoded, event) {
+				t.Errorf("round trip = %#v, want %#v", decoded, event)
+			}
+		})
+	}
+}
+
+func TestTextJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		text Text
+		json string
+	}{
+		{
+			name: "plain",
+			text: Text{{Text: "hello"}},
+			json: `["hello"]`,
+		},
+		{
+			name: "emotes",
+			text: Text{
+				{Text: "hi ", EmoteID: "25", EmoteText: "Kappa"},
+				{EmoteID: "1", EmoteText: ":)"},
+				{Text: " bye"},
+			},
+			json: `["hi ",{"emote":"25","name":"Kappa"},{"emote":"1","name":":)"}," bye"]`,
+		},
+		{
+			name: "cheer",
+			text: Text{{EmoteID: "cheer100", EmoteText: "Cheer100", Bits: 100, BitsColor: "#9c3ee8"}},
+			json: `[{"emote":"cheer100","name":"Cheer100","bits":100,"bits_color":"#9c3ee8"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := json.Marshal(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(enc) != test.json {
+				t.Errorf("marshal = %s, want %s", enc, test.json)
+			}
+
+			var decoded Text
+			if err = json.Unmarshal(enc, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, test.text) {
+				t.Errorf("unmarshal = %#v, want %#v", decoded, test.text)
+			}
+		})
+	}
+}
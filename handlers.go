@@ -0,0 +1,179 @@
+package retwitch
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+type HandlerConcurrency int
+
+const (
+	HandlerSerial HandlerConcurrency = iota
+	HandlerParallel
+)
+
+type handlerEntry struct {
+	kinds []LiveEventKind
+	fn    func(*LiveEvent)
+}
+
+type dispatcher struct {
+	client   *Client
+	mode     HandlerConcurrency
+	onPanic  func(error)
+	mu       sync.RWMutex
+	handlers []*handlerEntry
+	workermu sync.Mutex
+	workers  map[string]*liveStream
+	started  sync.Once
+}
+
+func (c *Client) On(fn func(*LiveEvent), kinds ...LiveEventKind) (remove func()) {
+	d := c.dispatcher
+	entry := &handlerEntry{kinds: kinds, fn: fn}
+
+	d.mu.Lock()
+	handlers := make([]*handlerEntry, len(d.handlers), len(d.handlers)+1)
+	copy(handlers, d.handlers)
+	d.handlers = append(handlers, entry)
+	d.mu.Unlock()
+
+	d.started.Do(func() {
+		sub := c.subscribe(SubscribeOptions{Overflow: OverflowUnbounded})
+		go d.run(sub.stream.events)
+	})
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		handlers := make([]*handlerEntry, 0, len(d.handlers))
+		for _, other := range d.handlers {
+			if other != entry {
+				handlers = append(handlers, other)
+			}
+		}
+		d.handlers = handlers
+	}
+}
+
+func (c *Client) OnMessage(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, MessageEvent)
+}
+
+func (c *Client) OnAction(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, ActionEvent)
+}
+
+func (c *Client) OnWhisper(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, WhisperEvent)
+}
+
+func (c *Client) OnSubscription(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, SubEvent, ResubEvent, SubGiftEvent, MysteryGiftEvent)
+}
+
+func (c *Client) OnRaid(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, RaidEvent)
+}
+
+func (c *Client) OnAnnouncement(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, AnnouncementEvent)
+}
+
+func (c *Client) OnModeration(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, BanEvent, TimeoutEvent, ClearChatEvent, DeleteMessageEvent)
+}
+
+func (c *Client) OnRoomState(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, RoomStateEvent)
+}
+
+func (c *Client) OnConnection(fn func(*LiveEvent)) (remove func()) {
+	return c.On(fn, DisconnectedEvent, ReconnectingEvent, ResumedEvent, RejoinFailedEvent)
+}
+
+func (d *dispatcher) run(events <-chan LiveEvent) {
+	for event := range events {
+		if d.mode == HandlerParallel {
+			for _, h := range d.matching(&event) {
+				go d.call(h, event)
+			}
+			continue
+		}
+
+		d.workermu.Lock()
+		worker, ok := d.workers[event.Channel]
+		if !ok {
+			worker = newLiveStream(0, OverflowUnbounded)
+			d.workers[event.Channel] = worker
+			go d.work(worker.events)
+		}
+		d.workermu.Unlock()
+
+		worker.push(d.client.ctx, event)
+	}
+
+	d.workermu.Lock()
+	defer d.workermu.Unlock()
+	for channel, worker := range d.workers {
+		worker.close()
+		delete(d.workers, channel)
+	}
+}
+
+func (d *dispatcher) stopWorker(channel string) {
+	d.workermu.Lock()
+	defer d.workermu.Unlock()
+
+	if worker, ok := d.workers[channel]; ok {
+		worker.close()
+		delete(d.workers, channel)
+	}
+}
+
+func (d *dispatcher) work(events <-chan LiveEvent) {
+	for event := range events {
+		for _, h := range d.matching(&event) {
+			d.call(h, event)
+		}
+	}
+}
+
+func (d *dispatcher) matching(event *LiveEvent) (matched []*handlerEntry) {
+	d.mu.RLock()
+	handlers := d.handlers
+	d.mu.RUnlock()
+
+	for _, h := range handlers {
+		if len(h.kinds) == 0 {
+			matched = append(matched, h)
+			continue
+		}
+
+		for _, kind := range h.kinds {
+			if kind == event.Kind {
+				matched = append(matched, h)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func (d *dispatcher) call(h *handlerEntry, event LiveEvent) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err := handlerPanic{Event: event, Value: recovered, Stack: debug.Stack()}
+			if d.onPanic != nil {
+				d.onPanic(err)
+			} else {
+				log.Printf("retwitch: %v\n%s", err, err.Stack)
+			}
+		}
+	}()
+
+	h.fn(&event)
+}
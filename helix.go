@@ -1,18 +1,34 @@
 package retwitch
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHelixRetries = 3
+	helixRetryMinDelay  = time.Duration(500) * time.Millisecond
+	helixRetryMaxDelay  = time.Duration(30) * time.Second
 )
 
 type HelixAPI struct {
 	http.Client
+	Cache   HelixCache
+	BaseURL string
 
-	useridCache map[string]string
+	rt          *helixrt
+	useridCache *lruCache
+	flights     flightGroup
 }
 
 type HelixCheermote struct {
@@ -32,12 +48,33 @@ type HelixChatBadge struct {
 }
 
 func (h *HelixAPI) GetUserID(login string) (id string, err error) {
-	if cachedid, iscached := h.useridCache[login]; iscached {
-		return cachedid, nil
+	if cachedid, iscached := h.useridCache.get(login); iscached {
+		return cachedid.(string), nil
 	}
 
+	found, err := h.flights.do("userid:"+login, func() (interface{}, error) {
+		var id string
+		err := h.cached("userid:"+login, helixUserIDTTL, false, &id, func() (err error) {
+			id, err = h.fetchUserID(login)
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		h.useridCache.set(login, id)
+		return id, nil
+	})
+	if err != nil {
+		return
+	}
+
+	return found.(string), nil
+}
+
+func (h *HelixAPI) fetchUserID(login string) (id string, err error) {
 	q := url.Values{"login": {login}}
-	url := "https://api.twitch.tv/helix/users?" + q.Encode()
+	url := h.endpoint("/users?" + q.Encode())
 
 	resp, err := h.getEnsureOK(url)
 	if err != nil {
@@ -60,19 +97,40 @@ func (h *HelixAPI) GetUserID(login string) (id string, err error) {
 		return
 	}
 
-	id = body.Data[0].ID
+	if len(body.Data) == 0 {
+		err = ErrNoSuchUser
+		return
+	}
 
-	h.useridCache[login] = id
+	id = body.Data[0].ID
 	return
 }
 
 func (h *HelixAPI) GetCheermotes(bcid string) (cheermotePrefixes []string, cheermoteInfo map[string]HelixCheermote, err error) {
+	return h.cheermotes(bcid, false)
+}
+
+func (h *HelixAPI) cheermotes(bcid string, fresh bool) (cheermotePrefixes []string, cheermoteInfo map[string]HelixCheermote, err error) {
+	var cheers struct {
+		Prefixes []string
+		Info     map[string]HelixCheermote
+	}
+
+	err = h.cached(cheermoteCacheKey(bcid), helixAssetTTL, fresh, &cheers, func() (err error) {
+		cheers.Prefixes, cheers.Info, err = h.fetchCheermotes(bcid)
+		return
+	})
+
+	return cheers.Prefixes, cheers.Info, err
+}
+
+func (h *HelixAPI) fetchCheermotes(bcid string) (cheermotePrefixes []string, cheermoteInfo map[string]HelixCheermote, err error) {
 	query := ""
 	if bcid != "" {
 		query = "?broadcaster_id=" + bcid
 	}
 
-	resp, err := h.getEnsureOK("https://api.twitch.tv/helix/bits/cheermotes" + query)
+	resp, err := h.getEnsureOK(h.endpoint("/bits/cheermotes" + query))
 	if err != nil {
 		return
 	}
@@ -137,17 +195,29 @@ func (h *HelixAPI) GetCheermotesFor(username string) (cheermotePrefixes []string
 }
 
 func (h *HelixAPI) GetGlobalChatBadges() (badges map[string]HelixChatBadge, err error) {
-	resp, err := h.getEnsureOK("https://api.twitch.tv/helix/chat/badges/global")
-	if err != nil {
+	err = h.cached(badgeCacheKey("global"), helixAssetTTL, false, &badges, func() (err error) {
+		badges, err = h.fetchChatBadges(h.endpoint("/chat/badges/global"))
 		return
-	}
+	})
 
-	defer resp.Body.Close()
-	return findHelixChatBadges(resp.Body)
+	return
 }
 
 func (h *HelixAPI) GetChannelChatBadges(bcid string) (badges map[string]HelixChatBadge, err error) {
-	resp, err := h.getEnsureOK("https://api.twitch.tv/helix/chat/badges?broadcaster_id=" + bcid)
+	return h.channelChatBadges(bcid, false)
+}
+
+func (h *HelixAPI) channelChatBadges(bcid string, fresh bool) (badges map[string]HelixChatBadge, err error) {
+	err = h.cached(badgeCacheKey(bcid), helixAssetTTL, fresh, &badges, func() (err error) {
+		badges, err = h.fetchChatBadges(h.endpoint("/chat/badges?broadcaster_id=" + bcid))
+		return
+	})
+
+	return
+}
+
+func (h *HelixAPI) fetchChatBadges(url string) (badges map[string]HelixChatBadge, err error) {
+	resp, err := h.getEnsureOK(url)
 	if err != nil {
 		return
 	}
@@ -165,6 +235,10 @@ func (h *HelixAPI) GetChannelChatBadgesFor(username string) (badges map[string]H
 	return h.GetChannelChatBadges(bcid)
 }
 
+func (h *HelixAPI) CacheStats() CacheStats {
+	return h.useridCache.snapshot()
+}
+
 func findHelixChatBadges(rbody io.ReadCloser) (badges map[string]HelixChatBadge, err error) {
 	type ResponseVersion map[string]string
 	type ResponseBadge struct {
@@ -208,43 +282,274 @@ func findHelixChatBadges(rbody io.ReadCloser) (badges map[string]HelixChatBadge,
 	return
 }
 
+func (h *HelixAPI) SendWhisper(fromID string, toID string, message string) (err error) {
+	q := url.Values{"from_user_id": {fromID}, "to_user_id": {toID}}
+	resp, err := h.postEnsureOK(h.endpoint("/whispers?"+q.Encode()), map[string]string{
+		"message": message,
+	})
+	if err != nil {
+		return
+	}
+
+	return resp.Body.Close()
+}
+
+func (h *HelixAPI) endpoint(path string) string {
+	if h.BaseURL == "" {
+		return DefaultEndpoints.Helix + path
+	}
+
+	return h.BaseURL + path
+}
+
 func (h *HelixAPI) getEnsureOK(url string) (resp *http.Response, err error) {
 	resp, err = h.Get(url)
 	if err == nil && resp.StatusCode != http.StatusOK {
-		err = httpStatusError{resp}
+		err = helixStatusError(resp)
 	}
 
 	return
 }
 
+func (h *HelixAPI) postEnsureOK(url string, body interface{}) (resp *http.Response, err error) {
+	enc, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+
+	resp, err = h.Post(url, "application/json", bytes.NewReader(enc))
+	if err == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		err = helixStatusError(resp)
+	}
+
+	return
+}
+
+func helixStatusError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return httpStatusError{resp}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return httpStatusError{resp}
+	}
+
+	var helixErr struct {
+		Message string `json:"message"`
+	}
+
+	json.Unmarshal(body, &helixErr)
+	if scope := strings.TrimPrefix(helixErr.Message, "Missing scope: "); scope != helixErr.Message {
+		return missingScopeError{strings.TrimSpace(scope)}
+	}
+
+	return httpStatusError{resp}
+}
+
+type HelixRateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func (h *HelixAPI) RateLimit() HelixRateLimit {
+	h.rt.mu.Lock()
+	defer h.rt.mu.Unlock()
+	return h.rt.bucket
+}
+
 type helixrt struct {
-	auth *twitchauth
-	rt   http.RoundTripper
+	auth    TokenSource
+	rt      http.RoundTripper
+	retries int
+
+	mu     sync.Mutex
+	bucket HelixRateLimit
 }
 
-func getHelixAPI(auth *twitchauth) *HelixAPI {
+func NewHelixAPI(tokens TokenSource) *HelixAPI {
+	return getHelixAPI(tokens, nil, 0)
+}
+
+func getHelixAPI(auth TokenSource, transport http.RoundTripper, retries int) *HelixAPI {
+	if retries == 0 {
+		retries = defaultHelixRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	rt := &helixrt{auth: auth, rt: transport, retries: retries}
 	return &HelixAPI{
-		Client: http.Client{
-			Transport: &helixrt{auth: auth},
-		},
-		useridCache: map[string]string{},
+		Client:      http.Client{Transport: rt},
+		rt:          rt,
+		useridCache: newLRUCache(4*defaultCacheSize, 0),
 	}
 }
 
-func (hrt *helixrt) RoundTrip(req *http.Request) (*http.Response, error) {
-	if err := hrt.auth.update(); err != nil {
-		return nil, err
+func (hrt *helixrt) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	canReplay := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	refreshed := false
+
+	for attempt, sent := 0, false; ; sent = true {
+		if err = hrt.waitBucket(req.Context()); err != nil {
+			return
+		}
+
+		var token Token
+		if token, err = hrt.auth.Token(); err != nil {
+			return
+		}
+
+		sendreq := req.Clone(req.Context())
+		sendreq.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		sendreq.Header.Set("Client-Id", token.ClientID)
+
+		if sent && req.GetBody != nil {
+			if sendreq.Body, err = req.GetBody(); err != nil {
+				return
+			}
+		}
+
+		resp, err = hrt.rt.RoundTrip(sendreq)
+		if err != nil {
+			return
+		}
+
+		hrt.track(resp.Header)
+
+		if resp.StatusCode == http.StatusUnauthorized && !refreshed && canReplay && isInvalidToken(resp) {
+			refreshed = true
+
+			if _, err = hrt.auth.Refresh(token); errors.Is(err, ErrTokenNotRenewable) {
+				return resp, nil
+			} else if err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+
+			resp.Body.Close()
+			continue
+		}
+
+		if !isRetryable(req.Method, resp.StatusCode) || attempt >= hrt.retries || !canReplay {
+			return
+		}
+
+		delay := backoffDelay(attempt, helixRetryMinDelay, helixRetryMaxDelay)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if untilReset := time.Until(hrt.resetTime()); untilReset > delay {
+				delay = untilReset
+			}
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if err = sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		attempt++
 	}
+}
 
-	req = req.Clone(req.Context())
-	req.Header.Add("Authorization", hrt.auth.AccessToken)
-	req.Header.Add("Client-Id", hrt.auth.ClientID)
+func isInvalidToken(resp *http.Response) bool {
+	if strings.Contains(resp.Header.Get("WWW-Authenticate"), "invalid_token") {
+		return true
+	}
 
-	if hrt.rt == nil {
-		hrt.rt = http.DefaultTransport
+	// Helix doesn't always send the header, so fall back to the error body,
+	// putting it back afterwards for whoever reads the response next.
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var helixErr struct {
+		Message string `json:"message"`
+	}
+
+	return json.Unmarshal(body, &helixErr) == nil &&
+		strings.Contains(strings.ToLower(helixErr.Message), "invalid oauth token")
+}
+
+func (hrt *helixrt) waitBucket(ctx context.Context) error {
+	hrt.mu.Lock()
+	wait := time.Duration(0)
+	if hrt.bucket.Limit > 0 {
+		if hrt.bucket.Remaining > 0 {
+			hrt.bucket.Remaining--
+		} else {
+			wait = time.Until(hrt.bucket.Reset)
+		}
+	}
+	hrt.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	return sleepContext(ctx, wait)
+}
+
+func (hrt *helixrt) track(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("Ratelimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(header.Get("Ratelimit-Remaining"))
+	if err != nil {
+		return
 	}
 
-	// TODO: More gracefully handle token expiration
+	reset, err := strconv.ParseInt(header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	hrt.mu.Lock()
+	hrt.bucket = HelixRateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+	hrt.mu.Unlock()
+}
 
-	return hrt.rt.RoundTrip(req)
+func (hrt *helixrt) resetTime() time.Time {
+	hrt.mu.Lock()
+	defer hrt.mu.Unlock()
+	return hrt.bucket.Reset
+}
+
+func isRetryable(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+
+	// A 5xx doesn't say whether the request took effect, so only requests
+	// that are safe to repeat get sent again.
+	return status >= 500 && (method == "" || method == "GET" || method == "HEAD")
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return contextError(ctx)
+	}
 }
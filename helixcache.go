@@ -0,0 +1,237 @@
+package retwitch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	helixUserIDTTL      = time.Duration(24) * time.Hour
+	helixAssetTTL       = time.Duration(1) * time.Hour
+	fileCacheFlushDelay = time.Duration(2) * time.Second
+)
+
+type HelixCache interface {
+	Load(key string) (data []byte, ok bool)
+	Store(key string, data []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Caches that hold writes back can implement HelixCacheFlusher, and
+// Client.Close flushes them.
+type HelixCacheFlusher interface {
+	Flush() error
+}
+
+// FileCache batches its writes, so anything stored in the last couple of
+// seconds is only on disk after Flush. Client.Close flushes the cache it was
+// configured with; flush it yourself when using it elsewhere.
+type FileCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]fileCacheEntry
+	dirty   bool
+	flusher *time.Timer
+	err     error
+
+	writemu sync.Mutex
+}
+
+type fileCacheEntry struct {
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires"`
+}
+
+func NewFileCache(path string) (fc *FileCache, err error) {
+	fc = &FileCache{
+		path:    path,
+		entries: map[string]fileCacheEntry{},
+	}
+
+	enc, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fc, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// A damaged cache is no worse than none, and gets overwritten the next
+	// time something is stored.
+	if json.Unmarshal(enc, &fc.entries) != nil {
+		fc.entries = map[string]fileCacheEntry{}
+		return fc, nil
+	}
+
+	fc.prune()
+	return
+}
+
+func (fc *FileCache) Load(key string) (data []byte, ok bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	entry, ok := fc.entries[key]
+	if !ok {
+		return
+	}
+
+	if time.Now().After(entry.Expires) {
+		delete(fc.entries, key)
+		return nil, false
+	}
+
+	return entry.Data, true
+}
+
+func (fc *FileCache) Store(key string, data []byte, ttl time.Duration) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.entries[key] = fileCacheEntry{
+		Data:    data,
+		Expires: time.Now().Add(ttl),
+	}
+
+	return fc.changed()
+}
+
+func (fc *FileCache) Delete(key string) error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if _, ok := fc.entries[key]; !ok {
+		return nil
+	}
+
+	delete(fc.entries, key)
+	return fc.changed()
+}
+
+// A cold start stores a lot of entries at once, so writes are gathered up
+// and the file rewritten once things go quiet. Whatever went wrong with the
+// last write is reported by the next change.
+func (fc *FileCache) changed() (err error) {
+	err, fc.err = fc.err, nil
+	fc.dirty = true
+	if fc.flusher == nil {
+		fc.flusher = time.AfterFunc(fileCacheFlushDelay, func() {
+			fc.Flush()
+		})
+	}
+
+	return
+}
+
+func (fc *FileCache) Flush() (err error) {
+	fc.writemu.Lock()
+	defer fc.writemu.Unlock()
+
+	fc.mu.Lock()
+	if fc.flusher != nil {
+		fc.flusher.Stop()
+		fc.flusher = nil
+	}
+
+	if !fc.dirty {
+		fc.mu.Unlock()
+		return nil
+	}
+
+	fc.prune()
+	enc, err := json.Marshal(fc.entries)
+	fc.dirty = false
+	fc.mu.Unlock()
+
+	if err == nil {
+		err = fc.save(enc)
+	}
+
+	if err != nil {
+		fc.mu.Lock()
+		fc.dirty = true
+		fc.err = err
+		fc.mu.Unlock()
+	}
+
+	return
+}
+
+func (fc *FileCache) prune() {
+	now := time.Now()
+	for key, entry := range fc.entries {
+		if now.After(entry.Expires) {
+			delete(fc.entries, key)
+		}
+	}
+}
+
+func (fc *FileCache) save(enc []byte) (err error) {
+	// Write beside the real file and rename over it, so a crash mid-write
+	// never leaves a truncated cache behind.
+	tmp, err := ioutil.TempFile(filepath.Dir(fc.path), filepath.Base(fc.path)+".*")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(enc); err != nil {
+		tmp.Close()
+		return
+	}
+
+	if err = tmp.Close(); err != nil {
+		return
+	}
+
+	return os.Rename(tmp.Name(), fc.path)
+}
+
+func (h *HelixAPI) cached(key string, ttl time.Duration, fresh bool, value interface{}, fetch func() error) (err error) {
+	if h.Cache != nil && !fresh {
+		if data, ok := h.Cache.Load(key); ok && json.Unmarshal(data, value) == nil {
+			return nil
+		}
+	}
+
+	if err = fetch(); err != nil || h.Cache == nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	// The lookup itself succeeded, so a cache that can't be written to is
+	// no reason to fail it.
+	h.Cache.Store(key, data, ttl)
+	return nil
+}
+
+func (h *HelixAPI) forget(keys ...string) (err error) {
+	if h.Cache == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if delErr := h.Cache.Delete(key); delErr != nil && err == nil {
+			err = delErr
+		}
+	}
+
+	return
+}
+
+func cheermoteCacheKey(bcid string) string {
+	return "cheermotes:" + bcid
+}
+
+func badgeCacheKey(bcid string) string {
+	return "badges:" + bcid
+}
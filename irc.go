@@ -1,6 +1,7 @@
 package retwitch
 
 import (
+	"context"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -9,55 +10,65 @@ import (
 	"github.com/lrstanley/girc"
 )
 
-func ircConnect(username string, authcode string) (*girc.Client, error) {
+func ircNew(host string, port int, ssl bool, username string) *girc.Client {
+	authcode := ""
 	if username == "" {
 		authcode = "BLANK"
 		username = makeAnonUser()
 	}
 
-	irc := girc.New(girc.Config{
-		Server:     "irc.chat.twitch.tv",
-		Port:       6697,
-		SSL:        true,
+	return girc.New(girc.Config{
+		Server:     host,
+		Port:       port,
+		SSL:        ssl,
 		ServerPass: authcode,
 		Nick:       username,
 		User:       username,
+		// Sends are already paced by our own rate limiter, and girc's
+		// would only add its delay on top.
+		AllowFlood: true,
 		SupportedCaps: map[string][]string{
 			"twitch.tv/commands": nil,
 			"twitch.tv/tags":     nil,
 		},
 	})
+}
 
-	initchan := make(chan error)
-
-	irc.Handlers.Add(girc.INITIALIZED, func(client *girc.Client, event girc.Event) {
-		initchan <- nil
-	})
+func ircPassword(token string) string {
+	if token == "" {
+		return ""
+	}
 
-	go func() {
-		initchan <- irc.Connect()
-		close(initchan)
-	}()
+	return "oauth:" + strings.TrimPrefix(token, "oauth:")
+}
 
-	err := <-initchan
-	if err != nil {
-		return nil, err
+func isLoginFailure(event girc.Event) bool {
+	if event.Command != girc.NOTICE || len(event.Params) == 0 || event.Params[0] != "*" {
+		return false
 	}
 
-	return irc, nil
+	msg := event.Last()
+	return strings.HasPrefix(msg, "Login authentication failed") ||
+		strings.HasPrefix(msg, "Improperly formatted auth") ||
+		strings.HasPrefix(msg, "Login unsuccessful")
 }
 
 func ircToLiveEvent(ch *ChannelInfo, ircEvent girc.Event) (event LiveEvent) {
+	tags := ircEvent.Tags
 	event = LiveEvent{
-		Time:    ircEvent.Timestamp,
-		Channel: ircEvent.Params[0][1:],
-		Sender:  ircToSender(ircEvent),
-		Kind:    MessageEvent,
-		Message: ircToMessage(ch, ircEvent),
-	}
+		MessageID: tagString(tags, "id"),
+		Time:      ircTime(ircEvent),
+		Channel:   ircEvent.Params[0][1:],
+		RoomID:    tagString(tags, "room-id"),
+		Sender:    ircToSender(ircEvent),
+		Kind:      MessageEvent,
+		Message:   ircToMessage(ch, ircEvent),
+		Bits:      tagInt(tags, "bits"),
+		Nonce:     tagString(tags, "client-nonce"),
+		Reply:     ircToReplyParent(ircEvent),
 
-	if msgid, hasid := ircEvent.Tags.Get("id"); hasid {
-		event.MessageID = msgid
+		FirstMessage:     tagString(tags, "first-msg") == "1",
+		ReturningChatter: tagString(tags, "returning-chatter") == "1",
 	}
 
 	if ircEvent.IsAction() {
@@ -67,11 +78,59 @@ func ircToLiveEvent(ch *ChannelInfo, ircEvent girc.Event) (event LiveEvent) {
 	return
 }
 
+func ircToWhisperEvent(ircEvent girc.Event) LiveEvent {
+	return LiveEvent{
+		MessageID: tagString(ircEvent.Tags, "message-id"),
+		Time:      ircTime(ircEvent),
+		Sender:    ircToSender(ircEvent),
+		Kind:      WhisperEvent,
+		Message:   ircToMessage(nil, ircEvent),
+	}
+}
+
+func ircToReplyParent(ircEvent girc.Event) *ReplyParent {
+	tags := ircEvent.Tags
+	parentID, ok := tags.Get("reply-parent-msg-id")
+	if !ok {
+		return nil
+	}
+
+	reply := &ReplyParent{
+		MessageID: parentID,
+		Sender: Viewer{
+			User: tagString(tags, "reply-parent-user-login"),
+			ID:   tagString(tags, "reply-parent-user-id"),
+		},
+		Body:            tagString(tags, "reply-parent-msg-body"),
+		ThreadMessageID: tagString(tags, "reply-thread-parent-msg-id"),
+		ThreadUser:      tagString(tags, "reply-thread-parent-user-login"),
+	}
+
+	if display := tagString(tags, "reply-parent-display-name"); display != reply.Sender.User {
+		reply.Sender.Display = display
+	}
+
+	return reply
+}
+
+func ircTime(ircEvent girc.Event) time.Time {
+	sent, err := strconv.ParseInt(tagString(ircEvent.Tags, "tmi-sent-ts"), 10, 64)
+	if err == nil && sent > 0 {
+		return time.Unix(0, sent*int64(time.Millisecond))
+	}
+
+	return ircEvent.Timestamp
+}
+
 func ircToSender(ircEvent girc.Event) (sender Viewer) {
 	sender = Viewer{
 		User: ircEvent.Source.Name,
 	}
 
+	if login, ok := ircEvent.Tags.Get("login"); ok {
+		sender.User = login
+	}
+
 	if display, ok := ircEvent.Tags.Get("display-name"); ok {
 		if display != sender.User {
 			sender.Display = display
@@ -86,6 +145,26 @@ func ircToSender(ircEvent girc.Event) (sender Viewer) {
 		sender.Badges = strings.Split(badgespec, ",")
 	}
 
+	if infospec, ok := ircEvent.Tags.Get("badge-info"); ok && infospec != "" {
+		sender.BadgeInfo = strings.Split(infospec, ",")
+		for _, info := range sender.BadgeInfo {
+			if months := strings.TrimPrefix(info, "subscriber/"); months != info {
+				sender.SubMonths, _ = strconv.Atoi(months)
+			}
+		}
+	}
+
+	sender.ID = tagString(ircEvent.Tags, "user-id")
+	sender.Mod = tagString(ircEvent.Tags, "mod") == "1"
+	sender.Subscriber = tagString(ircEvent.Tags, "subscriber") == "1"
+	sender.Turbo = tagString(ircEvent.Tags, "turbo") == "1"
+	sender.VIP = tagString(ircEvent.Tags, "vip") == "1"
+	for _, badge := range sender.Badges {
+		if strings.HasPrefix(badge, "vip/") {
+			sender.VIP = true
+		}
+	}
+
 	return
 }
 
@@ -105,6 +184,27 @@ func ircToMessage(ch *ChannelInfo, ircEvent girc.Event) (message Text) {
 	return
 }
 
+func ircToNoticeError(ircEvent girc.Event) error {
+	err := noticeError{
+		Channel: strings.TrimPrefix(ircEvent.Params[0], "#"),
+		Message: ircEvent.Last(),
+	}
+
+	err.MsgID, _ = ircEvent.Tags.Get("msg-id")
+	return err
+}
+
+// girc drops tags from outgoing events unless the server offers the
+// message-tags capability, which Twitch never does. Writing the tags as part
+// of the command gets them onto the wire regardless.
+func ircTaggedEvent(tags girc.Tags, command string, params ...string) *girc.Event {
+	if len(tags) > 0 {
+		command = tags.String() + " " + command
+	}
+
+	return &girc.Event{Command: command, Params: params}
+}
+
 func (c *Client) onPrivmsg(ircClient *girc.Client, event girc.Event) {
 	if event.Params[0][0] == '#' {
 		ch, err := c.GetChannel(event.Params[0][1:])
@@ -112,26 +212,131 @@ func (c *Client) onPrivmsg(ircClient *girc.Client, event girc.Event) {
 			ch = nil
 		}
 
-		c.levs <- ircToLiveEvent(ch, event)
+		c.emit(ircToLiveEvent(ch, event))
+	}
+}
+
+func (c *Client) onWhisper(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) > 1 && event.Source != nil {
+		c.emit(ircToWhisperEvent(event))
+	}
+}
+
+func (c *Client) onUsernotice(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) == 0 || event.Params[0][0] != '#' {
+		return
+	}
+
+	ch, err := c.GetChannel(event.Params[0][1:])
+	if err != nil {
+		ch = nil
+	}
+
+	if lev, ok := ircToUserNoticeEvent(ch, event); ok {
+		c.emit(lev)
+	}
+}
+
+func (c *Client) onClearchat(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) > 0 && event.Params[0][0] == '#' {
+		c.emit(ircToClearChatEvent(event))
+	}
+}
+
+func (c *Client) onClearmsg(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) > 0 && event.Params[0][0] == '#' {
+		c.emit(ircToClearMsgEvent(event))
+	}
+}
+
+func (c *Client) onRoomstate(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) == 0 || event.Params[0][0] != '#' {
+		return
+	}
+
+	name := event.Params[0][1:]
+	ch := c.getRoomChannel(name, tagString(event.Tags, "room-id"))
+	room := ch.updateRoomState(event.Tags)
+
+	c.emit(LiveEvent{
+		Time:    ircTime(event),
+		Channel: name,
+		Kind:    RoomStateEvent,
+		Room:    &room,
+	})
+}
+
+func (c *Client) onWelcome(ircClient *girc.Client, event girc.Event) {
+	c.signalWelcome(nil)
+}
+
+func (c *Client) onNotice(ircClient *girc.Client, event girc.Event) {
+	if isLoginFailure(event) {
+		c.signalWelcome(loginError{event.Last()})
 	}
 }
 
-func (c *Client) waitIRC(channel string, replies ...string) <-chan girc.Event {
+func (c *Client) onReconnect(ircClient *girc.Client, event girc.Event) {
+	ircClient.Close()
+}
+
+func (c *Client) onUserstate(ircClient *girc.Client, event girc.Event) {
+	if len(event.Params) == 0 || !strings.HasPrefix(event.Params[0], "#") {
+		return
+	}
+
+	badges, _ := event.Tags.Get("badges")
+	mod, _ := event.Tags.Get("mod")
+	c.limiter.setElevated(event.Params[0][1:], isElevatedUserstate(badges, mod))
+}
+
+func (c *Client) waitIRC(channel string, replies ...string) (evch <-chan girc.Event, cancel func()) {
 	replySet := make(map[string]struct{}, len(replies))
 	for _, cmd := range replies {
 		replySet[cmd] = struct{}{}
 	}
 
-	evch := make(chan girc.Event)
-	c.irc.Handlers.AddTmp(girc.ALL_EVENTS, 0, func(_ *girc.Client, event girc.Event) bool {
-		if _, ok := replySet[event.Command]; ok && event.Params[0] == channel {
-			evch <- event
-			return true
+	replych := make(chan girc.Event, 1)
+	cuid := c.irc.Handlers.Add(girc.ALL_EVENTS, func(_ *girc.Client, event girc.Event) {
+		if _, ok := replySet[event.Command]; ok && len(event.Params) > 0 && event.Params[0] == channel {
+			select {
+			case replych <- event:
+			default:
+			}
 		}
-		return false
 	})
 
-	return evch
+	cancel = func() {
+		c.irc.Handlers.Remove(cuid)
+	}
+
+	return replych, cancel
+}
+
+func (c *Client) waitReply(ctx context.Context, evch <-chan girc.Event) (event girc.Event, err error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, replyTimeout)
+		defer cancel()
+	}
+
+	select {
+	case event = <-evch:
+	case <-ctx.Done():
+		err = contextError(ctx)
+	case <-c.ctx.Done():
+		err = ErrClosed
+	}
+
+	return
+}
+
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+
+	return ctx.Err()
 }
 
 func makeAnonUser() string {
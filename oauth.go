@@ -0,0 +1,177 @@
+package retwitch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	deviceGrantType       = "urn:ietf:params:oauth:grant-type:device_code"
+	deviceSlowDownBackoff = time.Duration(5) * time.Second
+)
+
+type OAuthApp struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+
+	AuthURL    string
+	HTTPClient *http.Client
+}
+
+type DeviceLogin struct {
+	UserCode        string
+	VerificationURI string
+	ExpiresAt       time.Time
+	Interval        time.Duration
+
+	deviceCode string
+	scopes     []string
+}
+
+func NewOAuthState() (state string, err error) {
+	buf := make([]byte, 16)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+func (a OAuthApp) AuthorizeURL(scopes []string, state string) string {
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {a.ClientID},
+		"redirect_uri":  {a.RedirectURI},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+	}
+
+	return a.endpoint("/authorize?" + q.Encode())
+}
+
+func (a OAuthApp) ExchangeCode(code string) (token Token, err error) {
+	token, err = a.requestToken(url.Values{
+		"client_id":     {a.ClientID},
+		"client_secret": {a.ClientSecret},
+		"code":          {code},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {a.RedirectURI},
+	})
+
+	token.ClientID = a.ClientID
+	return
+}
+
+func (a OAuthApp) StartDeviceLogin(scopes []string) (login DeviceLogin, err error) {
+	resp, err := a.httpClient().PostForm(a.endpoint("/device"), url.Values{
+		"client_id": {a.ClientID},
+		"scopes":    {strings.Join(scopes, " ")},
+	})
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = oauthResponseError(resp)
+		return
+	}
+
+	type DeviceResponse struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+	}
+
+	var dr DeviceResponse
+	dec := json.NewDecoder(resp.Body)
+	if err = dec.Decode(&dr); err != nil {
+		return
+	}
+
+	login = DeviceLogin{
+		UserCode:        dr.UserCode,
+		VerificationURI: dr.VerificationURI,
+		ExpiresAt:       time.Now().Add(time.Duration(dr.ExpiresIn) * time.Second),
+		Interval:        time.Duration(dr.Interval) * time.Second,
+		deviceCode:      dr.DeviceCode,
+		scopes:          scopes,
+	}
+
+	return
+}
+
+func (a OAuthApp) WaitDeviceLogin(ctx context.Context, login DeviceLogin) (token Token, err error) {
+	ctx, cancel := context.WithDeadline(ctx, login.ExpiresAt)
+	defer cancel()
+
+	q := url.Values{
+		"client_id":   {a.ClientID},
+		"device_code": {login.deviceCode},
+		"grant_type":  {deviceGrantType},
+		"scopes":      {strings.Join(login.scopes, " ")},
+	}
+	if a.ClientSecret != "" {
+		q.Set("client_secret", a.ClientSecret)
+	}
+
+	interval := login.Interval
+	for {
+		if err = sleepContext(ctx, interval); err != nil {
+			return
+		}
+
+		token, err = a.requestToken(q)
+		if err == nil {
+			token.ClientID = a.ClientID
+			return
+		}
+
+		oerr, ok := err.(oauthError)
+		switch {
+		case ok && oerr.Message == "authorization_pending":
+		case ok && oerr.Message == "slow_down":
+			interval += deviceSlowDownBackoff
+		default:
+			return
+		}
+	}
+}
+
+func (a OAuthApp) endpoint(path string) string {
+	if a.AuthURL == "" {
+		return DefaultEndpoints.Auth + path
+	}
+
+	return a.AuthURL + path
+}
+
+func (a OAuthApp) httpClient() *http.Client {
+	if a.HTTPClient == nil {
+		return http.DefaultClient
+	}
+
+	return a.HTTPClient
+}
+
+func oauthResponseError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	if dec.Decode(&body) != nil || body.Message == "" {
+		return httpStatusError{resp}
+	}
+
+	return oauthError{resp.StatusCode, body.Message}
+}
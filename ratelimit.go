@@ -0,0 +1,227 @@
+package retwitch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimitPolicy int
+
+const (
+	RateLimitDelay RateLimitPolicy = iota
+	RateLimitQueue
+	RateLimitReject
+)
+
+const (
+	chatRateLimit     = 20
+	chatModRateLimit  = 100
+	chatRatePeriod    = time.Duration(30) * time.Second
+	joinRateLimit     = 20
+	joinRatePeriod    = time.Duration(10) * time.Second
+	defaultQueueLimit = 16
+)
+
+type RateLimitUsage struct {
+	Used   int
+	Queued int
+	Limit  int
+	Period time.Duration
+}
+
+type rateWindow struct {
+	period time.Duration
+	sent   []time.Time
+}
+
+type rateLimiter struct {
+	mu         sync.Mutex
+	policy     RateLimitPolicy
+	queueLimit int
+	chats      map[string]*rateWindow
+	elevated   map[string]bool
+	joins      rateWindow
+}
+
+func newRateLimiter(policy RateLimitPolicy, queueLimit int) *rateLimiter {
+	if queueLimit <= 0 {
+		queueLimit = defaultQueueLimit
+	}
+
+	return &rateLimiter{
+		policy:     policy,
+		queueLimit: queueLimit,
+		chats:      map[string]*rateWindow{},
+		elevated:   map[string]bool{},
+		joins:      rateWindow{period: joinRatePeriod},
+	}
+}
+
+func (w *rateWindow) prune(now time.Time) {
+	n := 0
+	for n < len(w.sent) && now.Sub(w.sent[n]) >= w.period {
+		n++
+	}
+
+	w.sent = w.sent[n:]
+}
+
+func (w *rateWindow) usage(now time.Time, limit int) (usage RateLimitUsage) {
+	w.prune(now)
+
+	usage = RateLimitUsage{Used: len(w.sent), Limit: limit, Period: w.period}
+	for _, at := range w.sent {
+		if at.After(now) {
+			usage.Queued++
+		}
+	}
+
+	return
+}
+
+func (w *rateWindow) release(at time.Time) {
+	for i := len(w.sent) - 1; i >= 0; i-- {
+		if w.sent[i].Equal(at) {
+			w.sent = append(w.sent[:i], w.sent[i+1:]...)
+			return
+		}
+	}
+}
+
+func (l *rateLimiter) chatWindow(channel string) *rateWindow {
+	w, ok := l.chats[channel]
+	if !ok {
+		w = &rateWindow{period: chatRatePeriod}
+		l.chats[channel] = w
+	}
+
+	return w
+}
+
+func (l *rateLimiter) chatLimit(channel string) int {
+	if l.elevated[channel] {
+		return chatModRateLimit
+	}
+
+	return chatRateLimit
+}
+
+func (l *rateLimiter) reserve(w *rateWindow, limit int, policy RateLimitPolicy) (at time.Time, err error) {
+	now := time.Now()
+	usage := w.usage(now, limit)
+
+	at = now
+	if usage.Used >= limit {
+		at = w.sent[usage.Used-limit].Add(w.period)
+	}
+	if usage.Used > 0 && w.sent[usage.Used-1].After(at) {
+		at = w.sent[usage.Used-1]
+	}
+
+	if at.After(now) {
+		switch {
+		case policy == RateLimitReject:
+			return at, ErrRateLimited
+		case policy == RateLimitQueue && usage.Queued >= l.queueLimit:
+			return at, ErrRateLimited
+		}
+	}
+
+	w.sent = append(w.sent, at)
+	return
+}
+
+func (l *rateLimiter) waitChat(ctx context.Context, channel string) error {
+	return l.wait(ctx, l.policy, func() (*rateWindow, int) {
+		return l.chatWindow(channel), l.chatLimit(channel)
+	})
+}
+
+func (l *rateLimiter) waitJoin(ctx context.Context) error {
+	return l.wait(ctx, l.policy, l.joinWindow)
+}
+
+// Rejoining after a reconnect has nobody to hand an ErrRateLimited back to,
+// so it always waits its turn.
+func (l *rateLimiter) waitRejoin(ctx context.Context) error {
+	return l.wait(ctx, RateLimitDelay, l.joinWindow)
+}
+
+func (l *rateLimiter) joinWindow() (*rateWindow, int) {
+	return &l.joins, joinRateLimit
+}
+
+func (l *rateLimiter) wait(ctx context.Context, policy RateLimitPolicy, window func() (*rateWindow, int)) error {
+	l.mu.Lock()
+	w, limit := window()
+	at, err := l.reserve(w, limit, policy)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if err != nil || delay <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		w.release(at)
+		l.mu.Unlock()
+		return contextError(ctx)
+	}
+}
+
+func (l *rateLimiter) setElevated(channel string, elevated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.elevated[channel] = elevated
+}
+
+// Twitch counts messages against the user rather than the join, so a
+// parted channel's window has to run out before it can go. Any emptied since
+// an earlier part are swept up along with it.
+func (l *rateLimiter) forget(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.elevated, channel)
+
+	now := time.Now()
+	for name, w := range l.chats {
+		if w.prune(now); len(w.sent) == 0 {
+			delete(l.chats, name)
+		}
+	}
+}
+
+func (c *Client) ChatRateUsage(channel string) RateLimitUsage {
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	return c.limiter.chatWindow(channel).usage(time.Now(), c.limiter.chatLimit(channel))
+}
+
+func (c *Client) JoinRateUsage() RateLimitUsage {
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+	return c.limiter.joins.usage(time.Now(), joinRateLimit)
+}
+
+func isElevatedUserstate(badges string, mod string) bool {
+	if mod == "1" {
+		return true
+	}
+
+	for _, badge := range strings.Split(badges, ",") {
+		if strings.HasPrefix(badge, "broadcaster/") || strings.HasPrefix(badge, "vip/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,107 @@
+package retwitch
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitReserve(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     RateLimitPolicy
+		queueLimit int
+		elevated   bool
+		sends      int
+		wantDelay  int
+		wantErr    int
+	}{
+		{name: "within budget", policy: RateLimitReject, sends: 20},
+		{name: "reject over budget", policy: RateLimitReject, sends: 25, wantErr: 5},
+		{name: "delay over budget", policy: RateLimitDelay, sends: 25, wantDelay: 5},
+		{name: "queue up to its limit", policy: RateLimitQueue, queueLimit: 3, sends: 25, wantDelay: 3, wantErr: 2},
+		{name: "elevated budget", policy: RateLimitReject, elevated: true, sends: 100},
+		{name: "elevated over budget", policy: RateLimitReject, elevated: true, sends: 101, wantErr: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := newRateLimiter(test.policy, test.queueLimit)
+			l.setElevated("chan", test.elevated)
+
+			start := time.Now()
+			delayed, rejected := 0, 0
+			for i := 0; i < test.sends; i++ {
+				at, err := l.reserve(l.chatWindow("chan"), l.chatLimit("chan"), l.policy)
+				switch {
+				case err == ErrRateLimited:
+					rejected++
+				case err != nil:
+					t.Fatal(err)
+				case at.Sub(start) >= chatRatePeriod:
+					delayed++
+				case at.After(time.Now()):
+					t.Errorf("send %d delayed by less than a window", i)
+				}
+			}
+
+			if delayed != test.wantDelay || rejected != test.wantErr {
+				t.Errorf("delayed %d and rejected %d, want %d and %d",
+					delayed, rejected, test.wantDelay, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateWindowReservations(t *testing.T) {
+	w := &rateWindow{period: time.Minute}
+	l := newRateLimiter(RateLimitDelay, 0)
+
+	first, _ := l.reserve(w, 2, RateLimitDelay)
+	second, _ := l.reserve(w, 2, RateLimitDelay)
+	third, _ := l.reserve(w, 2, RateLimitDelay)
+	fourth, _ := l.reserve(w, 2, RateLimitDelay)
+
+	if !third.Equal(first.Add(time.Minute)) {
+		t.Errorf("third send at %v, want a window after the first", third.Sub(first))
+	}
+
+	if fourth.Before(third) {
+		t.Errorf("fourth send at %v, before the third", fourth.Sub(first))
+	}
+
+	usage := w.usage(time.Now(), 2)
+	if usage.Used != 4 || usage.Queued != 2 {
+		t.Errorf("usage = %+v, want 4 used and 2 queued", usage)
+	}
+
+	w.release(fourth)
+	if usage = w.usage(time.Now(), 2); usage.Queued != 1 {
+		t.Errorf("%d queued after a release, want 1", usage.Queued)
+	}
+
+	if usage = w.usage(second.Add(time.Minute), 2); usage.Used != 1 {
+		t.Errorf("%d used a window later, want 1", usage.Used)
+	}
+}
+
+func TestRateLimitForget(t *testing.T) {
+	l := newRateLimiter(RateLimitReject, 0)
+	l.setElevated("idle", true)
+	l.chatWindow("idle")
+	for i := 0; i < chatRateLimit; i++ {
+		if err := l.waitChat(context.Background(), "chan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l.forget("idle")
+	l.forget("chan")
+	if _, ok := l.chats["idle"]; ok || len(l.elevated) != 0 {
+		t.Errorf("idle channel's limits were kept")
+	}
+
+	if err := l.waitChat(context.Background(), "chan"); err != ErrRateLimited {
+		t.Errorf("send after parting got %v, want it rate limited", err)
+	}
+}
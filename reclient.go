@@ -1,40 +1,163 @@
 package retwitch
 
 import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+
 	"github.com/lrstanley/girc"
 )
 
 type Client struct {
-	appAuth  *twitchauth
-	helix    *HelixAPI
-	irc      *girc.Client
-	levs     chan LiveEvent
-	channels map[string]*ChannelInfo // TODO: Memory leak
-	badges   map[string]HelixChatBadge
+	login  string
+	tokens TokenSource
+
+	oauth     OAuthApp
+	helixURL  string
+	transport http.RoundTripper
+	dialer    Dialer
+
+	helixmu      sync.Mutex
+	helixCache   HelixCache
+	helixRetries int
+	appTokens    TokenSource
+	helix        *HelixAPI
+	userHelix    *HelixAPI
+
+	tokenmu      sync.Mutex
+	tokenInfo    *TokenInfo
+	tokenInvalid func(error)
+
+	irc *girc.Client
+
+	submu      sync.RWMutex
+	subs       []*subscriber
+	events     *subscriber
+	dispatcher *dispatcher
+
+	chanmu   sync.Mutex
+	channels *lruCache
+	pinned   map[string]*ChannelInfo
+	assets   *lruCache
+	flights  flightGroup
+
+	limiter   *rateLimiter
+	sendmu    sync.Mutex
+	sendLocks map[string]*sync.Mutex
+
+	connmu  sync.Mutex
+	welcome chan error
+	lost    chan error
+	settled chan struct{}
+	joined  map[string]struct{}
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+	closeOnce sync.Once
 }
 
 func (c *Client) Helix() (*HelixAPI, error) {
 	var err error
 
-	if c.appAuth == nil {
-		c.appAuth, err = getDefaultAuth()
+	c.helixmu.Lock()
+	defer c.helixmu.Unlock()
+
+	if c.appTokens == nil {
+		c.appTokens, err = getDefaultAuth(c.oauth)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if c.helix == nil {
-		c.helix = getHelixAPI(c.appAuth)
+		c.helix = c.newHelixAPI(c.appTokens)
 	}
 
 	return c.helix, nil
 }
 
-func (c *Client) Join(channel string) (err error) {
+func (c *Client) UserHelix() (*HelixAPI, error) {
+	if c.login == "" || c.tokens == nil {
+		return nil, ErrAnonymous
+	}
+
+	c.helixmu.Lock()
+	defer c.helixmu.Unlock()
+
+	if c.userHelix == nil {
+		token, err := c.tokens.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if token.ClientID == "" {
+			return nil, errNoClientID
+		}
+
+		c.userHelix = c.newHelixAPI(c.tokens)
+	}
+
+	return c.userHelix, nil
+}
+
+func (c *Client) newHelixAPI(tokens TokenSource) (helix *HelixAPI) {
+	helix = getHelixAPI(tokens, c.transport, c.helixRetries)
+	helix.BaseURL = c.helixURL
+	helix.Cache = c.helixCache
+	return
+}
+
+func (c *Client) Whisper(user string, text string) (err error) {
+	helix, err := c.UserHelix()
+	if err != nil {
+		return
+	}
+
+	if err = c.requireScope(scopeManageWhispers); err != nil {
+		return
+	}
+
+	fromID, err := helix.GetUserID(c.login)
+	if err != nil {
+		return
+	}
+
+	toID, err := helix.GetUserID(user)
+	if err != nil {
+		return
+	}
+
+	return helix.SendWhisper(fromID, toID, text)
+}
+
+func (c *Client) Join(channel string) error {
+	return c.JoinContext(context.Background(), channel)
+}
+
+func (c *Client) JoinContext(ctx context.Context, channel string) (err error) {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	if err = c.requireScope(scopeChatRead); err != nil {
+		return
+	}
+
+	if err = c.limiter.waitJoin(ctx); err != nil {
+		return
+	}
+
+	return c.confirmJoin(ctx, channel)
+}
+
+func (c *Client) confirmJoin(ctx context.Context, channel string) (err error) {
 	channel = "#" + channel
-	evch := c.waitIRC(
+	evch, cancel := c.waitIRC(
 		channel,
-		girc.JOIN,
+		"ROOMSTATE",
+		girc.NOTICE,
 		girc.ERR_BANNEDFROMCHAN,
 		girc.ERR_INVITEONLYCHAN,
 		girc.ERR_BADCHANNELKEY,
@@ -45,17 +168,163 @@ func (c *Client) Join(channel string) (err error) {
 		girc.ERR_TOOMANYTARGETS,
 		girc.ERR_UNAVAILRESOURCE,
 	)
+	defer cancel()
 
+	// ROOMSTATE is the last thing Twitch sends after a join, so waiting for
+	// it keeps the join's USERSTATE from being mistaken for a send reply.
 	c.irc.Cmd.Join(channel)
-	result := <-evch
-	if result.Command == girc.JOIN {
-		// c.getChannelInfo(channel)
+	result, err := c.waitReply(ctx, evch)
+	if err != nil {
+		c.abandonJoin(channel[1:])
+		return
+	}
+
+	if result.Command == "ROOMSTATE" {
+		// girc runs the ROOMSTATE handler alongside this waiter, so make sure
+		// the room state is in before handing the channel back.
+		ch := c.pinChannel(channel[1:], tagString(result.Tags, "room-id"))
+		ch.updateRoomState(result.Tags)
+
+		c.connmu.Lock()
+		c.joined[channel[1:]] = struct{}{}
+		c.connmu.Unlock()
 		return nil
 	}
 
+	if result.Command == girc.NOTICE {
+		return ircToNoticeError(result)
+	}
+
 	return &girc.ErrEvent{Event: &result}
 }
 
+// A join given up on may still go through later, so part it again rather
+// than be left in a channel nobody is tracking.
+func (c *Client) abandonJoin(channel string) {
+	c.connmu.Lock()
+	_, joined := c.joined[channel]
+	c.connmu.Unlock()
+
+	if !joined && c.ctx.Err() == nil {
+		c.irc.Cmd.Part("#" + channel)
+	}
+}
+
+func (c *Client) JoinMany(channels ...string) (errs map[string]error) {
+	type joinResult struct {
+		channel string
+		err     error
+	}
+
+	results := make(chan joinResult, len(channels))
+	for _, channel := range channels {
+		go func(channel string) {
+			results <- joinResult{channel, c.Join(channel)}
+		}(channel)
+	}
+
+	for range channels {
+		result := <-results
+		if result.err != nil {
+			if errs == nil {
+				errs = map[string]error{}
+			}
+
+			errs[result.channel] = result.err
+		}
+	}
+
+	return
+}
+
+func (c *Client) Part(channel string) {
+	c.connmu.Lock()
+	delete(c.joined, channel)
+	c.connmu.Unlock()
+
+	c.irc.Cmd.Part("#" + channel)
+
+	c.sendmu.Lock()
+	delete(c.sendLocks, "#"+channel)
+	c.sendmu.Unlock()
+
+	c.limiter.forget(channel)
+	c.dispatcher.stopWorker(channel)
+
+	c.unpinChannel(channel)
+}
+
+func (c *Client) Channels() (channels []string) {
+	channels = c.joinedChannels()
+	sort.Strings(channels)
+	return
+}
+
+func (c *Client) joinedChannels() (channels []string) {
+	c.connmu.Lock()
+	defer c.connmu.Unlock()
+
+	channels = make([]string, 0, len(c.joined))
+	for channel := range c.joined {
+		channels = append(channels, channel)
+	}
+
+	return
+}
+
+func (c *Client) emit(event LiveEvent) {
+	c.submu.RLock()
+	subs := c.subs
+	c.submu.RUnlock()
+
+	// A full blocking subscriber only gets waited on once everyone else
+	// has the event, and alongside any other full ones.
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		if !sub.filter.Match(&event) || sub.stream.offer(event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(stream *liveStream) {
+			defer wg.Done()
+			stream.wait(c.ctx, event)
+		}(sub.stream)
+	}
+
+	wg.Wait()
+}
+
+func (c *Client) Close() (err error) {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		<-c.stopped
+		c.closeStreams()
+
+		if flusher, ok := c.helixCache.(HelixCacheFlusher); ok {
+			err = flusher.Flush()
+		}
+	})
+
+	return
+}
+
+func (c *Client) closeStreams() {
+	c.submu.Lock()
+	subs := c.subs
+	c.subs = nil
+	c.submu.Unlock()
+
+	for _, sub := range subs {
+		sub.stream.close()
+	}
+}
+
 func (c *Client) LiveEvents() (events <-chan LiveEvent) {
-	return c.levs
+	c.events.stream.claim()
+	return c.events.stream.events
+}
+
+func (c *Client) DroppedEvents() uint64 {
+	return c.events.stream.droppedEvents()
 }
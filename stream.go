@@ -0,0 +1,192 @@
+package retwitch
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	OverflowBlock OverflowPolicy = iota
+	OverflowDropOldest
+	OverflowDropNewest
+	OverflowUnbounded
+)
+
+const defaultEventBuffer = 24
+
+type liveStream struct {
+	dropped   uint64
+	unclaimed uint32
+
+	events chan LiveEvent
+	policy OverflowPolicy
+	mu     sync.RWMutex
+	done   bool
+	quit   chan struct{}
+	once   sync.Once
+
+	queuemu sync.Mutex
+	queue   []LiveEvent
+	wake    chan struct{}
+}
+
+func newLiveStream(size int, policy OverflowPolicy) *liveStream {
+	if size <= 0 {
+		size = defaultEventBuffer
+	}
+
+	s := &liveStream{
+		events: make(chan LiveEvent, size),
+		policy: policy,
+		quit:   make(chan struct{}),
+	}
+
+	if policy == OverflowUnbounded {
+		s.wake = make(chan struct{}, 1)
+		go s.pump()
+	}
+
+	return s
+}
+
+func (s *liveStream) push(ctx context.Context, event LiveEvent) {
+	if !s.offer(event) {
+		s.wait(ctx, event)
+	}
+}
+
+func (s *liveStream) offer(event LiveEvent) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.done {
+		return true
+	}
+
+	if atomic.LoadUint32(&s.unclaimed) != 0 {
+		// Nobody is reading yet, so keep the latest events for whoever
+		// turns up rather than holding up the connection for them.
+		s.dropOldest(event, false)
+		return true
+	}
+
+	switch s.policy {
+	case OverflowBlock:
+		select {
+		case s.events <- event:
+		default:
+			return false
+		}
+
+	case OverflowDropNewest:
+		select {
+		case s.events <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+
+	case OverflowDropOldest:
+		s.dropOldest(event, true)
+
+	case OverflowUnbounded:
+		s.queuemu.Lock()
+		s.queue = append(s.queue, event)
+		s.queuemu.Unlock()
+
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return true
+}
+
+func (s *liveStream) wait(ctx context.Context, event LiveEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.done {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	case <-ctx.Done():
+		atomic.AddUint64(&s.dropped, 1)
+	case <-s.quit:
+	}
+}
+
+func (s *liveStream) dropOldest(event LiveEvent, count bool) {
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+			if count {
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		default:
+		}
+	}
+}
+
+func (s *liveStream) holdUntilClaimed() {
+	atomic.StoreUint32(&s.unclaimed, 1)
+}
+
+func (s *liveStream) claim() {
+	atomic.StoreUint32(&s.unclaimed, 0)
+}
+
+func (s *liveStream) pump() {
+	defer close(s.events)
+
+	for {
+		s.queuemu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.queuemu.Unlock()
+
+		for i, event := range queue {
+			select {
+			case s.events <- event:
+			case <-s.quit:
+				atomic.AddUint64(&s.dropped, uint64(len(queue)-i))
+				return
+			}
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *liveStream) close() {
+	s.once.Do(func() {
+		close(s.quit)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.done = true
+		if s.policy != OverflowUnbounded {
+			close(s.events)
+		}
+	})
+}
+
+func (s *liveStream) droppedEvents() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
@@ -0,0 +1,144 @@
+package retwitch
+
+import (
+	"regexp"
+	"strings"
+)
+
+type EventFilter struct {
+	Channels []string
+	Kinds    []LiveEventKind
+	Senders  []string
+	Badges   []string
+	Pattern  *regexp.Regexp
+}
+
+type SubscribeOptions struct {
+	Filter   EventFilter
+	Buffer   int
+	Overflow OverflowPolicy
+}
+
+type Subscription struct {
+	Events <-chan LiveEvent
+
+	client *Client
+	sub    *subscriber
+}
+
+type subscriber struct {
+	filter EventFilter
+	stream *liveStream
+}
+
+func (f *EventFilter) Match(event *LiveEvent) bool {
+	if len(f.Channels) > 0 && !containsFold(f.Channels, event.Channel) {
+		return false
+	}
+
+	if len(f.Kinds) > 0 {
+		found := false
+		for _, kind := range f.Kinds {
+			if kind == event.Kind {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Senders) > 0 && !containsFold(f.Senders, event.Sender.User) {
+		return false
+	}
+
+	if len(f.Badges) > 0 && !event.Sender.HasBadge(f.Badges...) {
+		return false
+	}
+
+	if f.Pattern != nil && !f.Pattern.MatchString(event.Message.Plain()) {
+		return false
+	}
+
+	return true
+}
+
+func (v *Viewer) HasBadge(badges ...string) bool {
+	for _, have := range v.Badges {
+		set := have
+		if slash := strings.IndexByte(have, '/'); slash >= 0 {
+			set = have[:slash]
+		}
+
+		for _, want := range badges {
+			if want == have || want == set {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, entry := range list {
+		if strings.EqualFold(strings.TrimPrefix(entry, "#"), value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Client) Subscribe(opts SubscribeOptions) *Subscription {
+	sub := c.subscribe(opts)
+	return &Subscription{
+		Events: sub.stream.events,
+		client: c,
+		sub:    sub,
+	}
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.client.unsubscribe(s.sub)
+}
+
+func (s *Subscription) DroppedEvents() uint64 {
+	return s.sub.stream.droppedEvents()
+}
+
+func (c *Client) subscribe(opts SubscribeOptions) *subscriber {
+	sub := &subscriber{
+		filter: opts.Filter,
+		stream: newLiveStream(opts.Buffer, opts.Overflow),
+	}
+
+	c.submu.Lock()
+	defer c.submu.Unlock()
+
+	if c.ctx.Err() != nil {
+		sub.stream.close()
+		return sub
+	}
+
+	subs := make([]*subscriber, len(c.subs), len(c.subs)+1)
+	copy(subs, c.subs)
+	c.subs = append(subs, sub)
+	return sub
+}
+
+func (c *Client) unsubscribe(sub *subscriber) {
+	c.submu.Lock()
+	subs := make([]*subscriber, 0, len(c.subs))
+	for _, other := range c.subs {
+		if other != sub {
+			subs = append(subs, other)
+		}
+	}
+	c.subs = subs
+	c.submu.Unlock()
+
+	sub.stream.close()
+}
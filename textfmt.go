@@ -45,6 +45,17 @@ func (t Text) String() string {
 	return b.String()
 }
 
+func (t Text) Plain() string {
+	b := &strings.Builder{}
+
+	for _, segment := range t {
+		b.WriteString(segment.Text)
+		b.WriteString(segment.EmoteText)
+	}
+
+	return b.String()
+}
+
 func (t Text) MarshalJSON() ([]byte, error) {
 	buf := &bytes.Buffer{}
 	buf.WriteRune('[')
@@ -92,6 +103,51 @@ func (t Text) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func (t *Text) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	type emoteSegment struct {
+		EmoteID   string `json:"emote"`
+		EmoteText string `json:"name,omitempty"`
+		Bits      int    `json:"bits,omitempty"`
+		BitsColor string `json:"bits_color,omitempty"`
+	}
+
+	segments := make([]TextSegment, 0, len(raw))
+	for _, entry := range raw {
+		if len(entry) > 0 && entry[0] == '"' {
+			var text string
+			if err := json.Unmarshal(entry, &text); err != nil {
+				return err
+			}
+
+			segments = append(segments, TextSegment{Text: text})
+			continue
+		}
+
+		var emote emoteSegment
+		if err := json.Unmarshal(entry, &emote); err != nil {
+			return err
+		}
+
+		if len(segments) == 0 || segments[len(segments)-1].EmoteID != "" {
+			segments = append(segments, TextSegment{})
+		}
+
+		segment := &segments[len(segments)-1]
+		segment.EmoteID = emote.EmoteID
+		segment.EmoteText = emote.EmoteText
+		segment.Bits = emote.Bits
+		segment.BitsColor = emote.BitsColor
+	}
+
+	*t = Text(segments)
+	return nil
+}
+
 type emoteLocation struct {
 	EmoteID    string
 	CheerValue int
@@ -174,11 +230,13 @@ func (c *ChannelInfo) parseIRCCheer(msgtext string) (locs []emoteLocation) {
 		return
 	}
 
-	if err := c.resolveCheermotes(); err != nil {
+	cheers, err := c.resolveCheermotes()
+	if err != nil {
 		return
 	}
 
-	m := c.cheerMatch.FindAllStringSubmatchIndex(msgtext, -1)
+	cheerInfos := cheers.info
+	m := cheers.match.FindAllStringSubmatchIndex(msgtext, -1)
 	locs = make([]emoteLocation, 0, len(m))
 
 	for _, match := range m {
@@ -189,9 +247,9 @@ func (c *ChannelInfo) parseIRCCheer(msgtext string) (locs []emoteLocation) {
 			continue
 		}
 
-		cheerInfo := c.cheerInfo[cmPrefix+"1"]
+		cheerInfo := cheerInfos[cmPrefix+"1"]
 		for cheerInfo.NextTierID != "" {
-			nextCheerInfo := c.cheerInfo[cheerInfo.NextTierID]
+			nextCheerInfo := cheerInfos[cheerInfo.NextTierID]
 			if cmValue < nextCheerInfo.CheerValue {
 				break
 			}
@@ -0,0 +1,182 @@
+package retwitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const tokenValidateInterval = time.Duration(1) * time.Hour
+
+const (
+	scopeChatRead       = "chat:read"
+	scopeChatEdit       = "chat:edit"
+	scopeManageWhispers = "user:manage:whispers"
+)
+
+type TokenInfo struct {
+	Login     string
+	UserID    string
+	ClientID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+func (info TokenInfo) HasScope(scope string) bool {
+	for _, have := range info.Scopes {
+		if have == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ValidateToken(accessToken string) (info TokenInfo, err error) {
+	return OAuthApp{}.ValidateToken(accessToken)
+}
+
+func (a OAuthApp) ValidateToken(accessToken string) (info TokenInfo, err error) {
+	return a.validateToken(context.Background(), accessToken)
+}
+
+func (a OAuthApp) validateToken(ctx context.Context, accessToken string) (info TokenInfo, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.endpoint("/validate"), nil)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Authorization", "OAuth "+strings.TrimPrefix(accessToken, "oauth:"))
+	resp, err := a.httpClient().Do(req)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		err = ErrTokenInvalid
+		return
+	} else if resp.StatusCode != http.StatusOK {
+		err = httpStatusError{resp}
+		return
+	}
+
+	type ValidateResponse struct {
+		ClientID  string   `json:"client_id"`
+		Login     string   `json:"login"`
+		UserID    string   `json:"user_id"`
+		Scopes    []string `json:"scopes"`
+		ExpiresIn int      `json:"expires_in"`
+	}
+
+	var vr ValidateResponse
+	dec := json.NewDecoder(resp.Body)
+	if err = dec.Decode(&vr); err != nil {
+		return
+	}
+
+	info = TokenInfo{
+		Login:    vr.Login,
+		UserID:   vr.UserID,
+		ClientID: vr.ClientID,
+		Scopes:   vr.Scopes,
+	}
+
+	if vr.ExpiresIn > 0 {
+		info.ExpiresAt = time.Now().Add(time.Duration(vr.ExpiresIn) * time.Second)
+	}
+
+	return
+}
+
+func (c *Client) ValidateToken() (info TokenInfo, err error) {
+	if c.tokens == nil {
+		return info, ErrAnonymous
+	}
+
+	token, err := c.tokens.Token()
+	if err != nil {
+		return
+	}
+
+	info, err = c.oauth.validateToken(c.ctx, token.AccessToken)
+	if errors.Is(err, ErrTokenInvalid) {
+		if token, refreshErr := c.tokens.Refresh(token); refreshErr == nil {
+			info, err = c.oauth.validateToken(c.ctx, token.AccessToken)
+		}
+	}
+
+	c.tokenmu.Lock()
+	if err == nil {
+		c.tokenInfo = &info
+	} else if errors.Is(err, ErrTokenInvalid) {
+		c.tokenInfo = nil
+	}
+	c.tokenmu.Unlock()
+
+	if errors.Is(err, ErrTokenInvalid) && c.tokenInvalid != nil {
+		c.tokenInvalid(err)
+	}
+
+	return
+}
+
+// Without a username, whoever the token belongs to is who the client logs
+// in as.
+func (c *Client) resolveLogin(ctx context.Context) (err error) {
+	token, err := c.tokens.Token()
+	if err != nil {
+		return
+	}
+
+	info, err := c.oauth.validateToken(ctx, token.AccessToken)
+	if errors.Is(err, ErrTokenInvalid) {
+		if token, refreshErr := c.tokens.Refresh(token); refreshErr == nil {
+			info, err = c.oauth.validateToken(ctx, token.AccessToken)
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	if info.Login == "" {
+		return errNoTokenLogin
+	}
+
+	c.login = info.Login
+	c.tokenInfo = &info
+	return
+}
+
+func (c *Client) validateTokens() {
+	// Twitch asks that user tokens be validated hourly, and may disconnect
+	// clients that don't.
+	for {
+		c.ValidateToken()
+
+		select {
+		case <-time.After(tokenValidateInterval):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) requireScope(scope string) error {
+	c.tokenmu.Lock()
+	info := c.tokenInfo
+	c.tokenmu.Unlock()
+
+	// Until a validation succeeds the scopes aren't known, so leave it to
+	// Twitch to refuse.
+	if info == nil || info.HasScope(scope) {
+		return nil
+	}
+
+	return missingScopeError{scope}
+}
+
+var errNoTokenLogin = errors.New("token doesn't belong to a user")